@@ -0,0 +1,124 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+
+// Conditions and condition Reasons for the NodeConfig object
+
+const (
+	// UserDataRenderedCondition reports on the successful rendering of the
+	// cloud-init user data and its storage in the user data secret, along
+	// with the network data if any and the metadata.
+	UserDataRenderedCondition clusterv1.ConditionType = "UserDataRendered"
+
+	// UserDataRenderFailedReason (Severity=Error) documents a NodeConfig whose
+	// user data could not be rendered or stored.
+	UserDataRenderFailedReason = "UserDataRenderFailed"
+
+	// NetworkDataRenderFailedReason (Severity=Error) documents a NodeConfig
+	// whose network data could not be rendered or stored.
+	NetworkDataRenderFailedReason = "NetworkDataRenderFailed"
+
+	// ProfileApplyFailedReason (Severity=Error) documents a NodeConfig whose
+	// NodeConfigProfile could not be found or chosen.
+	ProfileApplyFailedReason = "ProfileApplyFailed"
+
+	// MetaDataRenderFailedReason (Severity=Error) documents a NodeConfig
+	// whose metadata could not be rendered or stored.
+	MetaDataRenderFailedReason = "MetaDataRenderFailed"
+)
+
+const (
+	// BareMetalHostCreatedCondition reports on the existence of the
+	// BareMetalHost backing the NodeConfig, either created by the controller
+	// found already registered or claimed through the host selector.
+	BareMetalHostCreatedCondition clusterv1.ConditionType = "BareMetalHostCreated"
+
+	// BareMetalHostCreateFailedReason (Severity=Error) documents a failure
+	// creating the BareMetalHost or its BMC credentials secret.
+	BareMetalHostCreateFailedReason = "BareMetalHostCreateFailed"
+
+	// BareMetalHostUnavailableReason (Severity=Error) documents a
+	// BareMetalHost that exists but is not in a state that can be used
+	// by the NodeConfig.
+	BareMetalHostUnavailableReason = "BareMetalHostUnavailable"
+
+	// NoMatchingHostReason (Severity=Warning) documents a NodeConfig whose
+	// host selector matches no available BareMetalHost yet.
+	NoMatchingHostReason = "NoMatchingHost"
+
+	// HostClaimFailedReason (Severity=Error) documents a failure listing or
+	// claiming the BareMetalHosts matching the host selector.
+	HostClaimFailedReason = "HostClaimFailed"
+)
+
+const (
+	// HardwareRequirementsMetCondition reports on the inspected hardware of
+	// the BareMetalHost meeting the hardware requirements of the NodeConfig.
+	HardwareRequirementsMetCondition clusterv1.ConditionType = "HardwareRequirementsMet"
+
+	// WaitingForInspectionReason (Severity=Info) documents a NodeConfig
+	// whose BareMetalHost has not been inspected yet.
+	WaitingForInspectionReason = "WaitingForInspection"
+
+	// HardwareRequirementsNotMetReason (Severity=Error) documents a
+	// BareMetalHost whose inspected hardware does not meet the hardware
+	// requirements of the NodeConfig. The host is not provisioned.
+	HardwareRequirementsNotMetReason = "HardwareRequirementsNotMet"
+)
+
+const (
+	// HostAssociatedCondition reports on the NodeConfig image and user data
+	// being written to the BareMetalHost.
+	HostAssociatedCondition clusterv1.ConditionType = "HostAssociated"
+
+	// AssociateFailedReason (Severity=Error) documents a failure updating
+	// the BareMetalHost with the NodeConfig details.
+	AssociateFailedReason = "AssociateFailed"
+
+	// ImageNotSetReason (Severity=Warning) documents a NodeConfig that has
+	// no image, neither of its own nor from a NodeConfigProfile.
+	ImageNotSetReason = "ImageNotSet"
+)
+
+const (
+	// ProvisionedCondition reports on the BareMetalHost having finished
+	// writing the image to the host.
+	ProvisionedCondition clusterv1.ConditionType = "Provisioned"
+
+	// WaitingForProvisioningReason (Severity=Info) documents a NodeConfig
+	// whose BareMetalHost has not finished provisioning yet.
+	WaitingForProvisioningReason = "WaitingForProvisioning"
+
+	// ProvisioningFailedReason (Severity=Error) documents a BareMetalHost
+	// that reported an error while provisioning.
+	ProvisioningFailedReason = "ProvisioningFailed"
+)
+
+// Conditions and condition Reasons for the NodeConfigSet object
+
+const (
+	// NodeConfigsCreatedCondition reports on the NodeConfigSet having a
+	// NodeConfig for every host and none for the hosts it no longer lists.
+	NodeConfigsCreatedCondition clusterv1.ConditionType = "NodeConfigsCreated"
+
+	// NodeConfigsCreateFailedReason (Severity=Error) documents a
+	// NodeConfigSet whose template, hosts or NodeConfigs could not be read,
+	// or whose NodeConfigs could not be created or deleted.
+	NodeConfigsCreateFailedReason = "NodeConfigsCreateFailed"
+)
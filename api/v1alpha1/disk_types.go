@@ -0,0 +1,131 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// PartitionTableType defines the partition table of a disk.
+// +kubebuilder:validation:Enum=gpt;mbr
+type PartitionTableType string
+
+const (
+	// GPTPartitionTable is a GUID partition table
+	GPTPartitionTable PartitionTableType = "gpt"
+	// MBRPartitionTable is an MS-DOS partition table
+	MBRPartitionTable PartitionTableType = "mbr"
+)
+
+// Disk defines the partitioning of a data disk. It is rendered to the
+// cloud-init disk_setup module.
+type Disk struct {
+	// Device is the disk, e.g. /dev/sdb
+	Device string `json:"device"`
+
+	// TableType is the partition table type. Defaults to gpt.
+	// +optional
+	TableType PartitionTableType `json:"tableType,omitempty"`
+
+	// Layout specifies the sizes of the partitions in percent of the disk,
+	// e.g. [50, 50]. A single partition spanning the disk is made when
+	// empty.
+	// +optional
+	Layout []int32 `json:"layout,omitempty"`
+
+	// Overwrite allows partitioning a disk that already has a partition
+	// table. Defaults to false, so data disks survive a reprovisioning.
+	// +optional
+	Overwrite *bool `json:"overwrite,omitempty"`
+}
+
+// Filesystem defines a filesystem made on a device. It is rendered to the
+// cloud-init fs_setup module.
+type Filesystem struct {
+	// Device is the device the filesystem is made on: a disk, a partition
+	// such as /dev/sdb1 or a logical volume such as /dev/data/etcd
+	Device string `json:"device"`
+
+	// Partition selects the partition of the device, when the device is a
+	// disk: a partition number, "auto", "any" or "none"
+	// +optional
+	Partition string `json:"partition,omitempty"`
+
+	// Filesystem is the filesystem type, e.g. ext4 or xfs
+	Filesystem string `json:"filesystem"`
+
+	// Label is the filesystem label
+	// +optional
+	Label string `json:"label,omitempty"`
+
+	// Overwrite allows making the filesystem over an existing one.
+	// Defaults to false.
+	// +optional
+	Overwrite *bool `json:"overwrite,omitempty"`
+
+	// ExtraOpts specifies additional options passed to mkfs
+	// +optional
+	ExtraOpts []string `json:"extraOpts,omitempty"`
+}
+
+// Mount defines a filesystem mounted on the node. It is rendered to the
+// cloud-init mounts module, which writes it to /etc/fstab.
+type Mount struct {
+	// Device is the device or the filesystem label to mount, e.g. /dev/sdb1
+	// or LABEL=data
+	Device string `json:"device"`
+
+	// MountPoint is the directory the filesystem is mounted on
+	MountPoint string `json:"mountPoint"`
+
+	// Type is the filesystem type. Defaults to auto.
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Options specifies the mount options. Defaults to the cloud-init
+	// defaults, defaults,nofail,x-systemd.requires=cloud-init.service
+	// +optional
+	Options string `json:"options,omitempty"`
+}
+
+// VolumeGroup defines an LVM volume group and its logical volumes. It is
+// created by a generated bootcmd, which runs before the filesystems are
+// made, so the logical volumes can be listed in the filesystems.
+type VolumeGroup struct {
+	// Name is the name of the volume group
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9+_.][a-zA-Z0-9+_.-]*$`
+	Name string `json:"name"`
+
+	// PhysicalVolumes specifies the devices of the volume group. The
+	// bootcmd runs before the disks are partitioned, so they are whole
+	// disks or existing partitions.
+	// +kubebuilder:validation:MinItems=1
+	PhysicalVolumes []string `json:"physicalVolumes"`
+
+	// LogicalVolumes specifies the logical volumes of the volume group
+	// +optional
+	LogicalVolumes []LogicalVolume `json:"logicalVolumes,omitempty"`
+}
+
+// LogicalVolume defines an LVM logical volume, available as
+// /dev/<volume group>/<name>.
+type LogicalVolume struct {
+	// Name is the name of the logical volume
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9+_.][a-zA-Z0-9+_.-]*$`
+	Name string `json:"name"`
+
+	// Size is the size of the logical volume, either an absolute size such
+	// as 20G or a share of the volume group such as 100%FREE or 50%VG
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?[bBsSkKmMgGtTpPeE]?|[0-9]+%(VG|FREE|PVS))$`
+	Size string `json:"size"`
+}
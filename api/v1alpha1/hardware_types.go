@@ -0,0 +1,200 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// Storage defines where the image is written on the host.
+type Storage struct {
+	// RootDeviceHints select the disk the image is written to
+	// +optional
+	RootDeviceHints *RootDeviceHints `json:"rootDeviceHints,omitempty"`
+}
+
+// RootDeviceHints holds the hints for selecting the root disk. Every hint
+// set must match the disk. They are handed as is to the BareMetalHost.
+type RootDeviceHints struct {
+	// DeviceName is a Linux device name like /dev/sda. The hint must match
+	// the actual value exactly.
+	// +optional
+	DeviceName string `json:"deviceName,omitempty"`
+
+	// HCTL is a SCSI bus address like 0:0:0:0. The hint must match the
+	// actual value exactly.
+	// +optional
+	HCTL string `json:"hctl,omitempty"`
+
+	// Model is a vendor-specific device identifier. The hint can be a
+	// substring of the actual value.
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// Vendor is the name of the vendor or manufacturer of the device. The
+	// hint can be a substring of the actual value.
+	// +optional
+	Vendor string `json:"vendor,omitempty"`
+
+	// SerialNumber is the device serial number. The hint must match the
+	// actual value exactly.
+	// +optional
+	SerialNumber string `json:"serialNumber,omitempty"`
+
+	// MinSizeGigabytes is the minimum size of the device in gigabytes
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinSizeGigabytes int `json:"minSizeGigabytes,omitempty"`
+
+	// WWN is the unique storage identifier. The hint must match the actual
+	// value exactly.
+	// +optional
+	WWN string `json:"wwn,omitempty"`
+
+	// WWNWithExtension is the unique storage identifier with the vendor
+	// extension appended. The hint must match the actual value exactly.
+	// +optional
+	WWNWithExtension string `json:"wwnWithExtension,omitempty"`
+
+	// WWNVendorExtension is the unique vendor storage identifier. The hint
+	// must match the actual value exactly.
+	// +optional
+	WWNVendorExtension string `json:"wwnVendorExtension,omitempty"`
+
+	// Rotational selects spinning media when true and solid-state storage
+	// when false
+	// +optional
+	Rotational *bool `json:"rotational,omitempty"`
+}
+
+// RAID defines the RAID volumes created on the host before it is
+// provisioned. Either the hardware or the software volumes are set.
+type RAID struct {
+	// HardwareRAIDVolumes specifies the logical disks of the RAID
+	// controller. The first one is the root volume unless rootDeviceHints
+	// are set.
+	// +optional
+	HardwareRAIDVolumes []HardwareRAIDVolume `json:"hardwareRAIDVolumes,omitempty"`
+
+	// SoftwareRAIDVolumes specifies the software RAID devices. There are
+	// one or two of them, and the first one is a RAID-1 the image is
+	// written to.
+	// +kubebuilder:validation:MaxItems=2
+	// +optional
+	SoftwareRAIDVolumes []SoftwareRAIDVolume `json:"softwareRAIDVolumes,omitempty"`
+}
+
+// HardwareRAIDVolume defines a logical disk of the RAID controller.
+type HardwareRAIDVolume struct {
+	// Name is the name of the volume, unique within the host. Generated
+	// when not set.
+	// +kubebuilder:validation:MaxLength=64
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Level is the RAID level of the volume
+	// +kubebuilder:validation:Enum="0";"1";"2";"5";"6";"1+0";"5+0";"6+0"
+	Level string `json:"level"`
+
+	// SizeGibibytes is the size of the volume in GiB. The whole capacity of
+	// the disks is used when not set.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SizeGibibytes *int `json:"sizeGibibytes,omitempty"`
+
+	// Rotational selects disks with only spinning media when true, or only
+	// solid-state storage when false
+	// +optional
+	Rotational *bool `json:"rotational,omitempty"`
+
+	// NumberOfPhysicalDisks is the number of disks of the volume. Defaults
+	// to the minimum the RAID level needs.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	NumberOfPhysicalDisks *int `json:"numberOfPhysicalDisks,omitempty"`
+}
+
+// SoftwareRAIDVolume defines a software RAID device.
+type SoftwareRAIDVolume struct {
+	// Level is the RAID level of the device
+	// +kubebuilder:validation:Enum="0";"1";"1+0"
+	Level string `json:"level"`
+
+	// SizeGibibytes is the size of the device in GiB. The whole capacity of
+	// the disks is used when not set.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SizeGibibytes *int `json:"sizeGibibytes,omitempty"`
+
+	// PhysicalDisks select the disks of the device, at least two of them
+	// +optional
+	PhysicalDisks []RootDeviceHints `json:"physicalDisks,omitempty"`
+}
+
+// HardwareRequirements defines the hardware the host must have. They are
+// checked against the hardware details the bare metal operator inspected
+// before the host is provisioned.
+type HardwareRequirements struct {
+	// MinCPUs is the minimum number of CPUs
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinCPUs int `json:"minCPUs,omitempty"`
+
+	// MinRAMMebibytes is the minimum amount of RAM in mebibytes
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinRAMMebibytes int `json:"minRAMMebibytes,omitempty"`
+
+	// Disks specifies the disks the host must have. Each requirement is
+	// met by a different disk.
+	// +optional
+	Disks []DiskRequirement `json:"disks,omitempty"`
+
+	// MinNICs is the minimum number of NICs at least as fast as
+	// minNICSpeedGbps
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinNICs int `json:"minNICs,omitempty"`
+
+	// MinNICSpeedGbps is the minimum speed of the NICs counted by minNICs.
+	// When minNICs is not set, one NIC must be that fast.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinNICSpeedGbps int `json:"minNICSpeedGbps,omitempty"`
+}
+
+// DiskType is the type of a disk.
+// +kubebuilder:validation:Enum=HDD;SSD;NVME
+type DiskType string
+
+const (
+	// HDD is a rotational disk
+	HDD DiskType = "HDD"
+	// SSD is a solid state disk that is not NVMe
+	SSD DiskType = "SSD"
+	// NVME is an NVMe disk
+	NVME DiskType = "NVME"
+)
+
+// DiskRequirement defines a disk the host must have.
+type DiskRequirement struct {
+	// MinSizeGigabytes is the minimum size of the disk in gigabytes, of
+	// 2^30 bytes as the root device hints
+	// +kubebuilder:validation:Minimum=1
+	MinSizeGigabytes int `json:"minSizeGigabytes"`
+
+	// Type is the type of the disk. Any type meets the requirement when it
+	// is not set.
+	// +optional
+	Type DiskType `json:"type,omitempty"`
+}
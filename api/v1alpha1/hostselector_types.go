@@ -0,0 +1,48 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HostSelector selects a registered BareMetalHost for the NodeConfig
+// instead of registering one from the BMC details. Only the hosts that are
+// available and not consumed by anything else are selected.
+type HostSelector struct {
+	// MatchLabels selects the hosts having all of the labels
+	// +optional
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+
+	// MatchExpressions selects the hosts whose labels satisfy all of the
+	// requirements
+	// +optional
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+
+	// HardwareProfile selects the hosts the bare metal operator matched
+	// with the hardware profile
+	// +optional
+	HardwareProfile string `json:"hardwareProfile,omitempty"`
+}
+
+// LabelSelector returns the label selector of the hosts.
+func (s *HostSelector) LabelSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels:      s.MatchLabels,
+		MatchExpressions: s.MatchExpressions,
+	}
+}
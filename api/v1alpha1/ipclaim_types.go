@@ -0,0 +1,83 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// IPClaimNodeConfigLabel is set on the IPClaims of a NodeConfig to its name.
+	IPClaimNodeConfigLabel = "bootstrap.tmax.io/nodeconfig"
+
+	// IPClaimInterfaceLabel is set on the IPClaims of a NodeConfig to the
+	// name of the interface the address is for.
+	IPClaimInterfaceLabel = "bootstrap.tmax.io/interface"
+)
+
+// IPClaimSpec defines the desired state of IPClaim
+type IPClaimSpec struct {
+	// PoolRef references the IPPool the address is allocated from
+	PoolRef corev1.LocalObjectReference `json:"poolRef"`
+}
+
+// IPClaimStatus defines the observed state of IPClaim
+type IPClaimStatus struct {
+	// Address is the allocated address in CIDR notation, e.g.
+	// 192.168.100.10/24
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// Gateway is the gateway of the pool
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+
+	// Nameservers are the DNS servers of the pool
+	// +optional
+	Nameservers []string `json:"nameservers,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Pool",type="string",JSONPath=".spec.poolRef.name",description="IPPool of the claim"
+//+kubebuilder:printcolumn:name="Address",type="string",JSONPath=".status.address",description="Allocated address"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// IPClaim is the Schema for the ipclaims API. The NodeConfig controller
+// creates one for each interface of a NodeConfig that takes its address from
+// an IPPool.
+type IPClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPClaimSpec   `json:"spec,omitempty"`
+	Status IPClaimStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// IPClaimList contains a list of IPClaim
+type IPClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IPClaim{}, &IPClaimList{})
+}
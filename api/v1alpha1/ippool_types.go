@@ -0,0 +1,79 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IPPoolSpec defines the desired state of IPPool
+type IPPoolSpec struct {
+	// CIDR is the subnet the addresses are allocated from, e.g.
+	// 192.168.100.0/24
+	CIDR string `json:"cidr"`
+
+	// Gateway is the default gateway of the subnet. It is never allocated.
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+
+	// Nameservers specifies the DNS servers of the subnet
+	// +optional
+	Nameservers []string `json:"nameservers,omitempty"`
+
+	// Exclude specifies the addresses that are never allocated, either
+	// single addresses or ranges such as 192.168.100.1-192.168.100.20
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// IPPoolStatus defines the observed state of IPPool
+type IPPoolStatus struct {
+	// Allocations maps the names of the IPClaims to the addresses allocated
+	// to them. Addresses are allocated by updating the status, so
+	// concurrent allocations conflict instead of handing out an address
+	// twice.
+	// +optional
+	Allocations map[string]string `json:"allocations,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="CIDR",type="string",JSONPath=".spec.cidr",description="Subnet of the pool"
+//+kubebuilder:printcolumn:name="Gateway",type="string",JSONPath=".spec.gateway",description="Gateway of the subnet"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// IPPool is the Schema for the ippools API
+type IPPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPPoolSpec   `json:"spec,omitempty"`
+	Status IPPoolStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// IPPoolList contains a list of IPPool
+type IPPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IPPool{}, &IPPoolList{})
+}
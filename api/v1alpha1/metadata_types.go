@@ -0,0 +1,55 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+const (
+	// MetaDataSecretKey is the key of the metadata Secret the
+	// BareMetalHost reads the instance metadata from.
+	MetaDataSecretKey = "metaData"
+
+	// NodeIndexLabel holds the index of the node, used as .Index in the
+	// hostname template. It is set by the NodeConfigSet of the NodeConfig.
+	NodeIndexLabel = "bootstrap.tmax.io/index"
+)
+
+// Metadata keys set from the NodeConfig, which the custom keys cannot
+// override.
+const (
+	MetaDataInstanceIDKey    = "instance-id"
+	MetaDataHostnameKey      = "hostname"
+	MetaDataLocalHostnameKey = "local-hostname"
+)
+
+// Metadata defines the instance metadata of the node. It is rendered along
+// with the instance-id, the UID of the NodeConfig, and handed to the
+// BareMetalHost as its metadata.
+type Metadata struct {
+	// Hostname is the hostname of the node. It is a Go template, given
+	// .Name and .Namespace of the NodeConfig and .Index from its
+	// bootstrap.tmax.io/index label, e.g. worker-{{ .Index }}
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// LocalHostname is the local hostname of the node, templated as the
+	// hostname. Defaults to the hostname.
+	// +optional
+	LocalHostname string `json:"localHostname,omitempty"`
+
+	// Keys specifies additional metadata keys and values
+	// +optional
+	Keys map[string]string `json:"keys,omitempty"`
+}
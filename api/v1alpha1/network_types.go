@@ -0,0 +1,169 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NetworkDataSecretKey is the key of the network data Secret the
+// BareMetalHost reads the network configuration from.
+const NetworkDataSecretKey = "networkData"
+
+// Network defines the network configuration of the node. It is rendered to
+// cloud-init network config version 2 and handed to the BareMetalHost as its
+// network data.
+type Network struct {
+	// Ethernets specifies the physical interfaces, matched by MAC address
+	// +optional
+	Ethernets []Ethernet `json:"ethernets,omitempty"`
+
+	// Bonds specifies the bonds of the ethernets
+	// +optional
+	Bonds []Bond `json:"bonds,omitempty"`
+
+	// VLANs specifies the VLANs on top of the ethernets or the bonds
+	// +optional
+	VLANs []VLAN `json:"vlans,omitempty"`
+}
+
+// InterfaceConfig defines the addressing of a network interface.
+type InterfaceConfig struct {
+	// DHCP4 enables DHCP for IPv4
+	// +optional
+	DHCP4 *bool `json:"dhcp4,omitempty"`
+
+	// DHCP6 enables DHCP for IPv6
+	// +optional
+	DHCP6 *bool `json:"dhcp6,omitempty"`
+
+	// Addresses specifies the static addresses in CIDR notation, e.g.
+	// 192.168.0.10/24
+	// +optional
+	Addresses []string `json:"addresses,omitempty"`
+
+	// Gateway4 specifies the default IPv4 gateway
+	// +optional
+	Gateway4 string `json:"gateway4,omitempty"`
+
+	// Gateway6 specifies the default IPv6 gateway
+	// +optional
+	Gateway6 string `json:"gateway6,omitempty"`
+
+	// Nameservers specifies the DNS servers and search domains
+	// +optional
+	Nameservers *Nameservers `json:"nameservers,omitempty"`
+
+	// Routes specifies the static routes
+	// +optional
+	Routes []Route `json:"routes,omitempty"`
+
+	// MTU specifies the MTU of the interface
+	// +optional
+	MTU *int32 `json:"mtu,omitempty"`
+
+	// IPPoolRef references an IPPool to allocate an address of the
+	// interface from. The address is added to the addresses, and the
+	// gateway and nameservers of the pool are used unless set here.
+	// +optional
+	IPPoolRef *corev1.LocalObjectReference `json:"ipPoolRef,omitempty"`
+}
+
+// Ethernet defines a physical interface.
+type Ethernet struct {
+	// Name is the name the interface is given
+	Name string `json:"name"`
+
+	// MACAddress is the MAC address of the interface
+	MACAddress string `json:"macAddress"`
+
+	InterfaceConfig `json:",inline"`
+}
+
+// BondMode defines the bonding mode.
+// +kubebuilder:validation:Enum=balance-rr;active-backup;balance-xor;broadcast;"802.3ad";balance-tlb;balance-alb
+type BondMode string
+
+// Bond defines a bond of ethernets.
+type Bond struct {
+	// Name is the name of the bond
+	Name string `json:"name"`
+
+	// Interfaces specifies the names of the ethernets in the bond
+	Interfaces []string `json:"interfaces"`
+
+	// Mode specifies the bonding mode
+	// +optional
+	Mode BondMode `json:"mode,omitempty"`
+
+	// MIIMonitorInterval specifies how often the link state is checked,
+	// e.g. 100
+	// +optional
+	MIIMonitorInterval string `json:"miiMonitorInterval,omitempty"`
+
+	// LACPRate specifies how often LACPDUs are requested in 802.3ad mode
+	// +kubebuilder:validation:Enum=slow;fast
+	// +optional
+	LACPRate string `json:"lacpRate,omitempty"`
+
+	// TransmitHashPolicy specifies how the slave is selected in the
+	// balance-xor, 802.3ad and balance-tlb modes, e.g. layer3+4
+	// +optional
+	TransmitHashPolicy string `json:"transmitHashPolicy,omitempty"`
+
+	InterfaceConfig `json:",inline"`
+}
+
+// VLAN defines a VLAN interface.
+type VLAN struct {
+	// Name is the name of the VLAN interface
+	Name string `json:"name"`
+
+	// ID is the VLAN ID
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=4094
+	ID int32 `json:"id"`
+
+	// Link is the name of the ethernet or the bond the VLAN is on
+	Link string `json:"link"`
+
+	InterfaceConfig `json:",inline"`
+}
+
+// Nameservers defines the DNS configuration of an interface.
+type Nameservers struct {
+	// Addresses specifies the DNS servers
+	// +optional
+	Addresses []string `json:"addresses,omitempty"`
+
+	// Search specifies the search domains
+	// +optional
+	Search []string `json:"search,omitempty"`
+}
+
+// Route defines a static route.
+type Route struct {
+	// To is the destination in CIDR notation, e.g. 10.0.0.0/8
+	To string `json:"to"`
+
+	// Via is the gateway address
+	Via string `json:"via"`
+
+	// Metric is the metric of the route
+	// +optional
+	Metric *int32 `json:"metric,omitempty"`
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// NodeConfigPhase is a high-level indicator of where the NodeConfig and its
+// BareMetalHost are in the provisioning process.
+//
+// The phases move forward as follows:
+//
+//   Pending -> RenderingUserData -> Registering -> Inspecting -> Provisioning -> Provisioned
+//
+// Once the user data is rendered, the phase follows the provisioning state
+// of the BareMetalHost, so it can also move backwards, e.g. from Provisioned
+// to Deprovisioning and then Provisioning again when the host is
+// re-provisioned. Any phase can move to Failed; a Failed NodeConfig moves
+// back to the phase matching its BareMetalHost once the error is cleared.
+// +kubebuilder:validation:Enum=Pending;RenderingUserData;Registering;Inspecting;Provisioning;Provisioned;Failed;Deprovisioning
+type NodeConfigPhase string
+
+const (
+	// NodeConfigPhasePending is the first phase a NodeConfig is assigned
+	// by the controller after being created.
+	NodeConfigPhasePending = NodeConfigPhase("Pending")
+
+	// NodeConfigPhaseRenderingUserData is the phase while the cloud-init
+	// user data is rendered and stored into its secret.
+	NodeConfigPhaseRenderingUserData = NodeConfigPhase("RenderingUserData")
+
+	// NodeConfigPhaseRegistering is the phase while the BareMetalHost is
+	// created and its BMC credentials are checked. It matches the
+	// "registering" and "unmanaged" BareMetalHost states.
+	NodeConfigPhaseRegistering = NodeConfigPhase("Registering")
+
+	// NodeConfigPhaseInspecting is the phase while the hardware inventory
+	// of the host is collected. It matches the "inspecting" and
+	// "match profile" BareMetalHost states.
+	NodeConfigPhaseInspecting = NodeConfigPhase("Inspecting")
+
+	// NodeConfigPhaseProvisioning is the phase while the image is written
+	// to the host. It matches the "preparing", "ready", "available" and
+	// "provisioning" BareMetalHost states, since the host has already been
+	// handed the image by then.
+	NodeConfigPhaseProvisioning = NodeConfigPhase("Provisioning")
+
+	// NodeConfigPhaseProvisioned is the phase once the BareMetalHost is
+	// "provisioned" or "externally provisioned".
+	NodeConfigPhaseProvisioned = NodeConfigPhase("Provisioned")
+
+	// NodeConfigPhaseFailed is the phase when the user data cannot be
+	// rendered, the BareMetalHost cannot be used, or the BareMetalHost
+	// reports an error. The conditions tell which step failed.
+	NodeConfigPhaseFailed = NodeConfigPhase("Failed")
+
+	// NodeConfigPhaseDeprovisioning is the phase while the image is removed
+	// from the host. It matches the "deprovisioning" and "deleting"
+	// BareMetalHost states.
+	NodeConfigPhaseDeprovisioning = NodeConfigPhase("Deprovisioning")
+)
@@ -17,17 +17,32 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+
+	kubeadmv1beta2 "github.com/tmax-cloud/nodeconfig-operator/types/v1beta2"
+)
+
+const (
+	// NodeConfigFinalizer allows the controller to clean up the BareMetalHost
+	// before the NodeConfig is removed from the API server.
+	NodeConfigFinalizer = "nodeconfig.bootstrap.tmax.io"
 )
 
 // Format specifies the output format of the bootstrap data
-// +kubebuilder:validation:Enum=cloud-config
+// +kubebuilder:validation:Enum=cloud-config;ignition
 type Format string
 
 const (
 	// CloudConfig make the bootstrap data to be of cloud-config format
 	CloudConfig Format = "cloud-config"
+	// Ignition make the bootstrap data to be of Ignition v3 format
+	Ignition Format = "ignition"
+	// DefaultFormat is cloud-config
+	DefaultFormat Format = CloudConfig
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -35,11 +50,26 @@ const (
 
 // NodeConfigSpec defines the desired state of NodeConfig
 type NodeConfigSpec struct {
-	// BMC specifies the BMC configuration
-	BMC *BMC `json:"bmc"`
+	// BMC specifies the BMC configuration of the BareMetalHost registered
+	// for the NodeConfig. Exactly one of bmc and hostSelector must be set.
+	// +optional
+	BMC *BMC `json:"bmc,omitempty"`
 
-	// Image holds the details of the image to be provisioned.
-	Image *Image `json:"image"`
+	// HostSelector selects a registered BareMetalHost for the NodeConfig
+	// instead of registering one from bmc.
+	// +optional
+	HostSelector *HostSelector `json:"hostSelector,omitempty"`
+
+	// Image holds the details of the image to be provisioned. It can be
+	// left to the NodeConfigProfile.
+	// +optional
+	Image *Image `json:"image,omitempty"`
+
+	// ProfileRef references the NodeConfigProfile merged into the
+	// NodeConfig. When not set, the profile whose selector matches the
+	// NodeConfig labels is used, if any.
+	// +optional
+	ProfileRef *corev1.LocalObjectReference `json:"profileRef,omitempty"`
 
 	// Files specifies extra files to be passed to user_data upon creation.
 	// +optional
@@ -57,15 +87,95 @@ type NodeConfigSpec struct {
 	// +optional
 	NTP *NTP `json:"ntp,omitempty"`
 
-	// Format specifies the output format of the bootstrap data
+	// Packages specifies the package repositories and the packages
+	// installed on first boot
+	// +optional
+	Packages *Packages `json:"packages,omitempty"`
+
+	// Disks specifies the data disks partitioned on first boot
+	// +optional
+	Disks []Disk `json:"disks,omitempty"`
+
+	// VolumeGroups specifies the LVM volume groups created on boot
+	// +optional
+	VolumeGroups []VolumeGroup `json:"volumeGroups,omitempty"`
+
+	// Filesystems specifies the filesystems made on first boot
+	// +optional
+	Filesystems []Filesystem `json:"filesystems,omitempty"`
+
+	// Mounts specifies the filesystems mounted on the node
+	// +optional
+	Mounts []Mount `json:"mounts,omitempty"`
+
+	// Network specifies the network configuration of the node, handed to
+	// the BareMetalHost as its network data
+	// +optional
+	Network *Network `json:"network,omitempty"`
+
+	// Metadata specifies the instance metadata of the node, handed to the
+	// BareMetalHost as its metadata
+	// +optional
+	Metadata *Metadata `json:"metadata,omitempty"`
+
+	// Storage specifies the disk the image is written to
+	// +optional
+	Storage *Storage `json:"storage,omitempty"`
+
+	// RAID specifies the RAID volumes to create on the host before it is
+	// provisioned
+	// +optional
+	RAID *RAID `json:"raid,omitempty"`
+
+	// HardwareRequirements specifies the hardware the host must have to
+	// be provisioned
+	// +optional
+	HardwareRequirements *HardwareRequirements `json:"hardwareRequirements,omitempty"`
+
+	// Role specifies how the node takes part in the cluster. Defaults to
+	// worker.
+	// +optional
+	Role NodeRole `json:"role,omitempty"`
+
+	// KubernetesVersion is the Kubernetes version the node runs, e.g.
+	// v1.22.2. It selects the kubeadm API version the kubeadm configuration
+	// is rendered with: v1beta1 before v1.15, v1beta2 before v1.22 and
+	// v1beta3 from v1.22 on. When empty, the configuration is rendered as
+	// v1beta2.
+	// +optional
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// Kubeadm specifies the kubeadm configuration used to bring up the node
+	// as its role says
+	// +optional
+	Kubeadm *KubeadmSpec `json:"kubeadm,omitempty"`
+
+	// ReprovisionPolicy specifies whether a provisioned host is provisioned
+	// again when the NodeConfig changes. Defaults to Never.
 	// +optional
-	// Format Format `json:"format,omitempty"`
+	ReprovisionPolicy ReprovisionPolicy `json:"reprovisionPolicy,omitempty"`
 
+	// DeletionPolicy specifies what happens to the BareMetalHost when the
+	// NodeConfig is deleted. Defaults to Detach.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// UnavailableHostPolicy specifies what to do when the BareMetalHost
+	// found for the NodeConfig cannot be used. Defaults to Fail.
+	// +optional
+	UnavailableHostPolicy UnavailableHostPolicy `json:"unavailableHostPolicy,omitempty"`
+
+	// Format specifies the output format of the bootstrap data.
+	// Defaults to cloud-config.
+	// +optional
+	Format Format `json:"format,omitempty"`
 }
 
 // NodeConfigStatus defines the observed state of NodeConfig
 type NodeConfigStatus struct {
-	// Ready indicates the BootstrapData field is ready to be consumed
+	// Ready indicates the NodeConfig has been rendered, associated with its
+	// BareMetalHost and provisioned. It mirrors the Ready condition, which
+	// summarizes the other conditions.
 	Ready bool `json:"ready,omitempty"`
 
 	// DataSecretName is the name of the secret that stores the bootstrap data script.
@@ -77,6 +187,15 @@ type NodeConfigStatus struct {
 	// namespace if not specified.
 	UserData *corev1.SecretReference `json:"userData,omitempty"`
 
+	// NetworkData references the Secret that holds the network data
+	// rendered from the network configuration.
+	// +optional
+	NetworkData *corev1.SecretReference `json:"networkData,omitempty"`
+
+	// MetaData references the Secret that holds the instance metadata.
+	// +optional
+	MetaData *corev1.SecretReference `json:"metaData,omitempty"`
+
 	// BootstrapData will be a cloud-init script for now.
 	//
 	// Deprecated: This field has been deprecated in v1alpha3 and
@@ -85,32 +204,84 @@ type NodeConfigStatus struct {
 	// +optional
 	// BootstrapData []byte `json:"bootstrapData,omitempty"`
 
-	// FailureReason will be set on non-retryable errors
+	// HostRef references the BareMetalHost claimed through the host
+	// selector.
 	// +optional
-	FailureReason string `json:"failureReason,omitempty"`
+	HostRef *corev1.ObjectReference `json:"hostRef,omitempty"`
 
-	// FailureMessage will be set in the event that there is a terminal problem
-	// reconciling the metal3machine and will contain a more verbose string suitable
-	// for logging and human consumption.
-	//
-	// This field should not be set for transitive errors that a controller
-	// faces that are expected to be fixed automatically over
-	// time (like service outages), but instead indicate that something is
-	// fundamentally wrong with the metal3machine's spec or the configuration of
-	// the controller, and that manual intervention is required. Examples
-	// of terminal errors would be invalid combinations of settings in the
-	// spec, values that are unsupported by the controller, or the
-	// responsible controller itself being critically misconfigured.
-	//
-	// Any transient errors that occur during the reconciliation of
-	// metal3machines can be added as events to the metal3machine object
-	// and/or logged in the controller's output.
+	// AppliedProfile records the NodeConfigProfile merged into the user
+	// data last rendered.
+	// +optional
+	AppliedProfile *AppliedProfile `json:"appliedProfile,omitempty"`
+
+	// ObservedGeneration is the latest generation of the NodeConfig spec
+	// the controller has successfully reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// UserDataHash is the sha256 hash of the user data currently stored in
+	// the user data secret.
+	// +optional
+	UserDataHash string `json:"userDataHash,omitempty"`
+
+	// ProvisionedUserDataHash is the sha256 hash of the user data the
+	// BareMetalHost was last handed for provisioning.
+	// +optional
+	ProvisionedUserDataHash string `json:"provisionedUserDataHash,omitempty"`
+
+	// NetworkDataHash is the sha256 hash of the network data currently
+	// stored in the network data secret.
 	// +optional
-	FailureMessage *string `json:"failureMessage,omitempty"`
+	NetworkDataHash string `json:"networkDataHash,omitempty"`
+
+	// ProvisionedNetworkDataHash is the sha256 hash of the network data the
+	// BareMetalHost was last handed for provisioning.
+	// +optional
+	ProvisionedNetworkDataHash string `json:"provisionedNetworkDataHash,omitempty"`
+
+	// MetaDataHash is the sha256 hash of the metadata currently stored in
+	// the metadata secret.
+	// +optional
+	MetaDataHash string `json:"metaDataHash,omitempty"`
+
+	// ProvisionedMetaDataHash is the sha256 hash of the metadata the
+	// BareMetalHost was last handed for provisioning.
+	// +optional
+	ProvisionedMetaDataHash string `json:"provisionedMetaDataHash,omitempty"`
+
+	// BootstrapTokenID is the ID of the bootstrap token generated for the
+	// node. It is cleared once the token is revoked.
+	// +optional
+	BootstrapTokenID string `json:"bootstrapTokenID,omitempty"`
+
+	// NodeName is the name of the Node registered by the host.
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// Phase represents the current phase of the NodeConfig provisioning.
+	// It follows the provisioning state of the BareMetalHost.
+	// +optional
+	Phase NodeConfigPhase `json:"phase,omitempty"`
+
+	// Conditions defines current service state of the NodeConfig.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+// AppliedProfile identifies the version of a NodeConfigProfile.
+type AppliedProfile struct {
+	// Name is the name of the NodeConfigProfile
+	Name string `json:"name"`
+
+	// Generation is the generation of the NodeConfigProfile spec
+	Generation int64 `json:"generation"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="NodeConfig provisioning phase"
+//+kubebuilder:printcolumn:name="Ready",type="boolean",JSONPath=".status.ready",description="NodeConfig is provisioned"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // NodeConfig is the Schema for the nodeconfigs API
 type NodeConfig struct {
@@ -121,6 +292,16 @@ type NodeConfig struct {
 	Status NodeConfigStatus `json:"status,omitempty"`
 }
 
+// GetConditions returns the list of conditions for a NodeConfig API object.
+func (nc *NodeConfig) GetConditions() clusterv1.Conditions {
+	return nc.Status.Conditions
+}
+
+// SetConditions will set the given conditions on a NodeConfig object.
+func (nc *NodeConfig) SetConditions(conditions clusterv1.Conditions) {
+	nc.Status.Conditions = conditions
+}
+
 //+kubebuilder:object:root=true
 
 // NodeConfigList contains a list of NodeConfig
@@ -184,9 +365,122 @@ type BMC struct {
 	// +optional
 	BootMode BootMode `json:"bootMode,omitempty"`
 
+	// CredentialsSecretRef references a Secret in the NodeConfig namespace
+	// holding the "username" and "password" of the BMC. It is handed as is
+	// to the BareMetalHost.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+
 	// ID/PW for authenticating with the BMC
-	Username string `json:"username"`
-	Password string `json:"password"`
+	//
+	// Deprecated: Use CredentialsSecretRef instead. Inline credentials are
+	// copied into a "<name>-bmc-secret" Secret by the controller.
+	// +optional
+	Username string `json:"username,omitempty"`
+	// +optional
+	Password string `json:"password,omitempty"`
+}
+
+// BMCCredentialsName returns the name of the Secret holding the BMC
+// credentials of the host.
+func (nc *NodeConfig) BMCCredentialsName() string {
+	if nc.Spec.BMC.CredentialsSecretRef != nil {
+		return nc.Spec.BMC.CredentialsSecretRef.Name
+	}
+	return nc.Name + "-bmc-secret"
+}
+
+// ReprovisionPolicy defines what to do with a provisioned host when the
+// NodeConfig changes.
+// +kubebuilder:validation:Enum=Never;OnImageChange;Always
+type ReprovisionPolicy string
+
+const (
+	// ReprovisionNever leaves a provisioned host untouched. Changes only
+	// update the user data secret.
+	ReprovisionNever ReprovisionPolicy = "Never"
+	// ReprovisionOnImageChange provisions the host again when the image
+	// changes.
+	ReprovisionOnImageChange ReprovisionPolicy = "OnImageChange"
+	// ReprovisionAlways provisions the host again when the image or the
+	// rendered user data, network data or metadata changes.
+	ReprovisionAlways ReprovisionPolicy = "Always"
+	// DefaultReprovisionPolicy is Never
+	DefaultReprovisionPolicy ReprovisionPolicy = ReprovisionNever
+)
+
+// ReprovisionPolicy returns the policy to apply to a provisioned host.
+func (nc *NodeConfig) ReprovisionPolicy() ReprovisionPolicy {
+	policy := nc.Spec.ReprovisionPolicy
+	if policy == "" {
+		return DefaultReprovisionPolicy
+	}
+	return policy
+}
+
+// DeletionPolicy defines what to do with the BareMetalHost when the
+// NodeConfig is deleted.
+// +kubebuilder:validation:Enum=Detach;Deprovision;Delete
+type DeletionPolicy string
+
+const (
+	// DeletionDetach leaves the BareMetalHost as it is.
+	DeletionDetach DeletionPolicy = "Detach"
+	// DeletionDeprovision clears the image and user data of the
+	// BareMetalHost and waits for it to be available again.
+	DeletionDeprovision DeletionPolicy = "Deprovision"
+	// DeletionDelete deletes the BareMetalHost, along with the BMC
+	// credentials secret it owns, and waits for it to be gone.
+	DeletionDelete DeletionPolicy = "Delete"
+	// DefaultDeletionPolicy is Detach
+	DefaultDeletionPolicy DeletionPolicy = DeletionDetach
+)
+
+// DeletionPolicy returns the policy to apply to the host on deletion.
+func (nc *NodeConfig) DeletionPolicy() DeletionPolicy {
+	policy := nc.Spec.DeletionPolicy
+	if policy == "" {
+		return DefaultDeletionPolicy
+	}
+	return policy
+}
+
+// UnavailableHostPolicy defines what to do when the BareMetalHost found for
+// a NodeConfig is not in a state that can be used.
+// +kubebuilder:validation:Enum=Fail;Wait;Recreate;Delete
+type UnavailableHostPolicy string
+
+const (
+	// UnavailableHostFail keeps both objects and marks the NodeConfig failed.
+	UnavailableHostFail UnavailableHostPolicy = "Fail"
+	// UnavailableHostWait requeues the NodeConfig with backoff until the
+	// host becomes available.
+	UnavailableHostWait UnavailableHostPolicy = "Wait"
+	// UnavailableHostRecreate deletes the BareMetalHost and registers it
+	// again.
+	UnavailableHostRecreate UnavailableHostPolicy = "Recreate"
+	// UnavailableHostDelete deletes both the BareMetalHost and the NodeConfig.
+	UnavailableHostDelete UnavailableHostPolicy = "Delete"
+	// DefaultUnavailableHostPolicy is Fail
+	DefaultUnavailableHostPolicy UnavailableHostPolicy = UnavailableHostFail
+)
+
+// Format returns the output format of the bootstrap data.
+func (nc *NodeConfig) Format() Format {
+	format := nc.Spec.Format
+	if format == "" {
+		return DefaultFormat
+	}
+	return format
+}
+
+// UnavailableHostPolicy returns the policy to apply to an unavailable host.
+func (nc *NodeConfig) UnavailableHostPolicy() UnavailableHostPolicy {
+	policy := nc.Spec.UnavailableHostPolicy
+	if policy == "" {
+		return DefaultUnavailableHostPolicy
+	}
+	return policy
 }
 
 // ChecksumType holds the algorithm name for the checksum
@@ -229,9 +523,11 @@ type Image struct {
 
 // CheckBMHDetails check if BMH value if filled
 func (nc *NodeConfig) CheckBMHDetails() bool {
+	hasCredentials := nc.Spec.BMC.CredentialsSecretRef != nil ||
+		(nc.Spec.BMC.Username != "" && nc.Spec.BMC.Password != "")
 	if nc.Spec.BMC.Address != "" &&
-		nc.Spec.BMC.Username != "" &&
-		nc.Spec.BMC.Password != "" &&
+		hasCredentials &&
+		nc.Spec.Image != nil &&
 		nc.Spec.Image.URL != "" &&
 		nc.Spec.Image.Checksum != "" {
 		return true
@@ -257,7 +553,35 @@ type File struct {
 	Encoding Encoding `json:"encoding,omitempty"`
 
 	// Content is the actual content of the file.
-	Content string `json:"content"`
+	// +optional
+	Content string `json:"content,omitempty"`
+
+	// ContentFrom reads the content of the file from a Secret or a
+	// ConfigMap in the NodeConfig namespace instead of Content. It is read
+	// every time the user data is rendered.
+	// +optional
+	ContentFrom *FileSource `json:"contentFrom,omitempty"`
+}
+
+// FileSource selects the key holding the content of a file. Exactly one of
+// Secret and ConfigMap is set.
+type FileSource struct {
+	// Secret selects a key of a Secret
+	// +optional
+	Secret *FileSourceKey `json:"secret,omitempty"`
+
+	// ConfigMap selects a key of a ConfigMap, from its data or binary data
+	// +optional
+	ConfigMap *FileSourceKey `json:"configMap,omitempty"`
+}
+
+// FileSourceKey selects a key of a Secret or a ConfigMap.
+type FileSourceKey struct {
+	// Name is the name of the Secret or the ConfigMap
+	Name string `json:"name"`
+
+	// Key is the key holding the content
+	Key string `json:"key"`
 }
 
 // User defines the input for a generated user in cloud-init.
@@ -306,6 +630,97 @@ type User struct {
 	SSHAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
 }
 
+// KubeadmSpec defines the kubeadm configuration of the node.
+type KubeadmSpec struct {
+	// ClusterConfiguration is the kubeadm configuration of the cluster,
+	// used by the controlPlaneInit role.
+	// +optional
+	ClusterConfiguration *kubeadmv1beta2.ClusterConfiguration `json:"clusterConfiguration,omitempty"`
+
+	// InitConfiguration is the kubeadm configuration for the init command,
+	// used by the controlPlaneInit role. `kubeadm init --config` runs after
+	// the cloudInitCommands.
+	// +optional
+	InitConfiguration *kubeadmv1beta2.InitConfiguration `json:"initConfiguration,omitempty"`
+
+	// JoinConfiguration is the kubeadm configuration for the join command,
+	// used by the worker and controlPlaneJoin roles. When set, it is written
+	// to the node and `kubeadm join --config` runs after the
+	// cloudInitCommands.
+	// +optional
+	JoinConfiguration *kubeadmv1beta2.JoinConfiguration `json:"joinConfiguration,omitempty"`
+
+	// CertificateKeySecretRef references a Secret holding the key that
+	// encrypts the control plane certificates uploaded by `kubeadm init
+	// --upload-certs`, under the certificateKey key. The controlPlaneInit
+	// role creates the Secret with a new key when it does not exist; the
+	// controlPlaneJoin role waits for it. It is ignored when the
+	// configuration sets the certificate key itself.
+	// +optional
+	CertificateKeySecretRef *corev1.LocalObjectReference `json:"certificateKeySecretRef,omitempty"`
+
+	// BootstrapTokenTTL is how long the bootstrap token generated for the
+	// node is valid. A token is generated when the JoinConfiguration uses
+	// token discovery without a token. Defaults to 24h.
+	// +optional
+	BootstrapTokenTTL *metav1.Duration `json:"bootstrapTokenTTL,omitempty"`
+}
+
+// NodeRole defines how a node takes part in the cluster.
+// +kubebuilder:validation:Enum=worker;controlPlaneInit;controlPlaneJoin
+type NodeRole string
+
+const (
+	// WorkerRole joins the node to the cluster as a worker.
+	WorkerRole NodeRole = "worker"
+	// ControlPlaneInitRole brings up the first control plane node of the
+	// cluster with `kubeadm init`.
+	ControlPlaneInitRole NodeRole = "controlPlaneInit"
+	// ControlPlaneJoinRole joins the node to the cluster as another control
+	// plane node.
+	ControlPlaneJoinRole NodeRole = "controlPlaneJoin"
+	// DefaultNodeRole is worker
+	DefaultNodeRole NodeRole = WorkerRole
+)
+
+// CertificateKeySecretKey is the key of the certificate key Secret that holds
+// the certificate key.
+const CertificateKeySecretKey = "certificateKey"
+
+// Role returns how the node takes part in the cluster.
+func (nc *NodeConfig) Role() NodeRole {
+	role := nc.Spec.Role
+	if role == "" {
+		return DefaultNodeRole
+	}
+	return role
+}
+
+// DefaultBootstrapTokenTTL is how long a generated bootstrap token is valid
+// unless the NodeConfig says otherwise.
+const DefaultBootstrapTokenTTL = 24 * time.Hour
+
+// BootstrapTokenTTL returns how long the generated bootstrap token is valid.
+func (nc *NodeConfig) BootstrapTokenTTL() time.Duration {
+	if nc.Spec.Kubeadm == nil || nc.Spec.Kubeadm.BootstrapTokenTTL == nil {
+		return DefaultBootstrapTokenTTL
+	}
+	return nc.Spec.Kubeadm.BootstrapTokenTTL.Duration
+}
+
+// NeedsBootstrapToken returns true when the JoinConfiguration uses token
+// discovery and leaves the token to the controller.
+func (nc *NodeConfig) NeedsBootstrapToken() bool {
+	if nc.Role() == ControlPlaneInitRole || nc.Spec.Kubeadm == nil || nc.Spec.Kubeadm.JoinConfiguration == nil {
+		return false
+	}
+	discovery := nc.Spec.Kubeadm.JoinConfiguration.Discovery
+	if discovery.File != nil {
+		return false
+	}
+	return discovery.BootstrapToken == nil || discovery.BootstrapToken.Token == ""
+}
+
 // NTP defines input for generated ntp in cloud-init
 type NTP struct {
 	// Servers specifies which NTP servers to use
@@ -18,9 +18,17 @@ package v1alpha1
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"text/template"
 
+	kubeadmtypes "github.com/tmax-cloud/nodeconfig-operator/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -28,6 +36,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
+// hctlRegexp matches a SCSI address, host:channel:target:lun.
+var hctlRegexp = regexp.MustCompile(`^\d+:\d+:\d+:\d+$`)
+
+// partitionRegexp matches the suffix a partition adds to its disk name.
+var partitionRegexp = regexp.MustCompile(`^p?[0-9]+$`)
+
 // log is for logging in this package.
 var nodeconfiglog = logf.Log.WithName("nodeconfig-resource")
 
@@ -59,32 +73,99 @@ func (r *NodeConfig) ValidateCreate() error {
 	nodeconfiglog.Info("validate create", "name", r.Name)
 	var errs []error
 
-	if r.Spec.Image == nil || r.Spec.Image.Checksum == "" {
+	// The image can be left to a NodeConfigProfile
+	if r.Spec.Image != nil && (r.Spec.Image.URL == "" || r.Spec.Image.Checksum == "") {
 		errs = append(errs, fmt.Errorf("image value not set"))
 		return errors.NewAggregate(errs)
 	}
-	if r.Spec.BMC == nil {
-		errs = append(errs, fmt.Errorf("BMC value not set"))
+	if err := r.hostValidation(); err != nil {
+		errs = append(errs, err)
 		return errors.NewAggregate(errs)
 	}
-
-	if err := r.osImageValidation(r.Spec.Image.URL, r.Spec.Image.Checksum); err != nil {
+	if err := r.kubeadmValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.filesValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.networkValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.metadataValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.hardwareValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.storageValidation(); err != nil {
 		errs = append(errs, err)
 		return errors.NewAggregate(errs)
 	}
-	if err := r.bmcValidation(r.Spec.BMC); err != nil {
+	if err := r.packagesValidation(); err != nil {
 		errs = append(errs, err)
 		return errors.NewAggregate(errs)
 	}
 
+	if r.Spec.Image != nil {
+		if err := r.osImageValidation(r.Spec.Image.URL, r.Spec.Image.Checksum); err != nil {
+			errs = append(errs, err)
+			return errors.NewAggregate(errs)
+		}
+	}
+	// The webhook cannot read a referenced secret, only inline credentials are checked
+	if r.Spec.BMC != nil && r.Spec.BMC.CredentialsSecretRef == nil {
+		if err := r.bmcValidation(r.Spec.BMC); err != nil {
+			errs = append(errs, err)
+			return errors.NewAggregate(errs)
+		}
+	}
+
 	return nil
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *NodeConfig) ValidateUpdate(old runtime.Object) error {
 	nodeconfiglog.Info("validate update", "name", r.Name)
+	var errs []error
+
+	if err := r.hostValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.kubeadmValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.filesValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.networkValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.metadataValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.hardwareValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.storageValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
+	if err := r.packagesValidation(); err != nil {
+		errs = append(errs, err)
+		return errors.NewAggregate(errs)
+	}
 
-	// TODO(user): fill in your validation logic upon object update.
 	return nil
 }
 
@@ -113,6 +194,471 @@ func (r *NodeConfig) osImageValidation(imageURL string, checksumURL string) erro
 	return nil
 }
 
+// hostValidation checks that the NodeConfig either registers its host from
+// the BMC details or selects a registered one, and that the selector is
+// valid.
+func (r *NodeConfig) hostValidation() error {
+	if r.Spec.BMC == nil && r.Spec.HostSelector == nil {
+		return fmt.Errorf("BMC value not set. set either bmc or hostSelector")
+	}
+	if r.Spec.BMC != nil && r.Spec.HostSelector != nil {
+		return fmt.Errorf("bmc and hostSelector are mutually exclusive")
+	}
+	if r.Spec.HostSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(r.Spec.HostSelector.LabelSelector()); err != nil {
+			return fmt.Errorf("invalid hostSelector: %v", err)
+		}
+		// A claimed host belongs to the pool, so it is never deleted
+		if r.Spec.DeletionPolicy == DeletionDelete {
+			return fmt.Errorf("deletionPolicy %s is not allowed with hostSelector", r.Spec.DeletionPolicy)
+		}
+		switch r.Spec.UnavailableHostPolicy {
+		case UnavailableHostRecreate, UnavailableHostDelete:
+			return fmt.Errorf("unavailableHostPolicy %s is not allowed with hostSelector", r.Spec.UnavailableHostPolicy)
+		}
+		return nil
+	}
+	return r.bmcCredentialsValidation(r.Spec.BMC)
+}
+
+// bmcCredentialsValidation checks that the BMC credentials are given either
+// by reference or inline, but not both.
+func (r *NodeConfig) bmcCredentialsValidation(bmcInfo *BMC) error {
+	hasInline := bmcInfo.Username != "" || bmcInfo.Password != ""
+	hasRef := bmcInfo.CredentialsSecretRef != nil && bmcInfo.CredentialsSecretRef.Name != ""
+	if hasInline && hasRef {
+		return fmt.Errorf("BMC credentialsSecretRef and username/password are mutually exclusive")
+	}
+	if !hasInline && !hasRef {
+		return fmt.Errorf("BMC credentials not set. set credentialsSecretRef")
+	}
+	return nil
+}
+
+func (r *NodeConfig) kubeadmValidation() error {
+	if r.Spec.KubernetesVersion != "" {
+		if _, err := kubeadmtypes.KubeVersionToKubeadmAPIGroupVersion(r.Spec.KubernetesVersion); err != nil {
+			return fmt.Errorf("invalid kubernetesVersion: %v", err)
+		}
+	}
+
+	kubeadm := r.Spec.Kubeadm
+	if kubeadm == nil {
+		if r.Role() == ControlPlaneJoinRole {
+			return fmt.Errorf("kubeadm.joinConfiguration must be set for the %s role", r.Role())
+		}
+		return nil
+	}
+
+	switch r.Role() {
+	case ControlPlaneInitRole:
+		if kubeadm.JoinConfiguration != nil {
+			return fmt.Errorf("kubeadm.joinConfiguration cannot be set for the %s role", r.Role())
+		}
+	default:
+		if kubeadm.InitConfiguration != nil || kubeadm.ClusterConfiguration != nil {
+			return fmt.Errorf("kubeadm.initConfiguration and kubeadm.clusterConfiguration cannot be set for the %s role", r.Role())
+		}
+		if r.Role() == ControlPlaneJoinRole && kubeadm.JoinConfiguration == nil {
+			return fmt.Errorf("kubeadm.joinConfiguration must be set for the %s role", r.Role())
+		}
+	}
+	return nil
+}
+
+// filesValidation checks that every file takes its content from one place.
+func (r *NodeConfig) filesValidation() error {
+	for _, f := range r.Spec.Files {
+		source := f.ContentFrom
+		if source == nil {
+			continue
+		}
+		if f.Content != "" {
+			return fmt.Errorf("file %s sets both content and contentFrom. set only one of them", f.Path)
+		}
+		if (source.Secret == nil) == (source.ConfigMap == nil) {
+			return fmt.Errorf("contentFrom of file %s must set exactly one of secret and configMap", f.Path)
+		}
+		key := source.Secret
+		if key == nil {
+			key = source.ConfigMap
+		}
+		if key.Name == "" || key.Key == "" {
+			return fmt.Errorf("contentFrom of file %s must set both name and key", f.Path)
+		}
+	}
+	return nil
+}
+
+// networkValidation checks that the interfaces of the network configuration
+// are well formed and that the bonds and VLANs refer to known interfaces.
+func (r *NodeConfig) networkValidation() error {
+	network := r.Spec.Network
+	if network == nil {
+		return nil
+	}
+	if r.Format() == Ignition {
+		return fmt.Errorf("network is not supported with the %s format", Ignition)
+	}
+
+	names := map[string]bool{}
+	addName := func(name string) error {
+		if name == "" {
+			return fmt.Errorf("network interface name not set")
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate network interface name %q", name)
+		}
+		names[name] = true
+		return nil
+	}
+
+	ethernets := map[string]bool{}
+	for _, e := range network.Ethernets {
+		if err := addName(e.Name); err != nil {
+			return err
+		}
+		if _, err := net.ParseMAC(e.MACAddress); err != nil {
+			return fmt.Errorf("invalid macAddress %q of ethernet %s", e.MACAddress, e.Name)
+		}
+		if err := interfaceValidation(e.Name, e.InterfaceConfig); err != nil {
+			return err
+		}
+		ethernets[e.Name] = true
+	}
+	bonds := map[string]bool{}
+	for _, b := range network.Bonds {
+		if err := addName(b.Name); err != nil {
+			return err
+		}
+		if len(b.Interfaces) == 0 {
+			return fmt.Errorf("bond %s has no interfaces", b.Name)
+		}
+		for _, i := range b.Interfaces {
+			if !ethernets[i] {
+				return fmt.Errorf("bond %s refers to unknown ethernet %q", b.Name, i)
+			}
+		}
+		if err := interfaceValidation(b.Name, b.InterfaceConfig); err != nil {
+			return err
+		}
+		bonds[b.Name] = true
+	}
+	for _, v := range network.VLANs {
+		if err := addName(v.Name); err != nil {
+			return err
+		}
+		if !ethernets[v.Link] && !bonds[v.Link] {
+			return fmt.Errorf("vlan %s refers to unknown link %q", v.Name, v.Link)
+		}
+		if err := interfaceValidation(v.Name, v.InterfaceConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataValidation checks that the hostname templates parse and that the
+// custom keys do not override the keys set from the NodeConfig.
+func (r *NodeConfig) metadataValidation() error {
+	md := r.Spec.Metadata
+	if md == nil {
+		return nil
+	}
+	if _, err := template.New("hostname").Parse(md.Hostname); err != nil {
+		return fmt.Errorf("invalid metadata hostname %q: %v", md.Hostname, err)
+	}
+	if _, err := template.New("localHostname").Parse(md.LocalHostname); err != nil {
+		return fmt.Errorf("invalid metadata localHostname %q: %v", md.LocalHostname, err)
+	}
+	for k := range md.Keys {
+		switch k {
+		case "":
+			return fmt.Errorf("empty metadata key")
+		case MetaDataInstanceIDKey, MetaDataHostnameKey, MetaDataLocalHostnameKey:
+			return fmt.Errorf("metadata key %q is set from the NodeConfig. use hostname and localHostname instead", k)
+		}
+	}
+	return nil
+}
+
+// hardwareValidation checks the root device hints and the RAID volumes.
+func (r *NodeConfig) hardwareValidation() error {
+	if r.Spec.Storage != nil {
+		if err := rootDeviceHintsValidation("rootDeviceHints", r.Spec.Storage.RootDeviceHints); err != nil {
+			return err
+		}
+	}
+
+	raid := r.Spec.RAID
+	if raid == nil {
+		return nil
+	}
+	if len(raid.HardwareRAIDVolumes) > 0 && len(raid.SoftwareRAIDVolumes) > 0 {
+		return fmt.Errorf("raid sets both hardwareRAIDVolumes and softwareRAIDVolumes. set only one of them")
+	}
+	for i, v := range raid.SoftwareRAIDVolumes {
+		if i == 0 && v.Level != "1" {
+			return fmt.Errorf("the first software RAID volume is the root volume and must be of level 1, not %q", v.Level)
+		}
+		if len(v.PhysicalDisks) == 1 {
+			return fmt.Errorf("software RAID volume %d has a single physical disk. set at least two", i)
+		}
+		for j := range v.PhysicalDisks {
+			if err := rootDeviceHintsValidation(fmt.Sprintf("physical disk %d of software RAID volume %d", j, i), &v.PhysicalDisks[j]); err != nil {
+				return err
+			}
+		}
+	}
+	names := map[string]bool{}
+	for _, v := range raid.HardwareRAIDVolumes {
+		if v.Name == "" {
+			continue
+		}
+		if names[v.Name] {
+			return fmt.Errorf("duplicate hardware RAID volume name %q", v.Name)
+		}
+		names[v.Name] = true
+	}
+	return nil
+}
+
+// storageValidation checks that the disks, volume groups and filesystems of
+// the node do not use a device twice and that the mount points are unique.
+func (r *NodeConfig) storageValidation() error {
+	spec := r.Spec
+	if len(spec.Disks) == 0 && len(spec.VolumeGroups) == 0 && len(spec.Filesystems) == 0 && len(spec.Mounts) == 0 {
+		return nil
+	}
+	if r.Format() == Ignition {
+		return fmt.Errorf("disks, volumeGroups, filesystems and mounts are not supported with the %s format", Ignition)
+	}
+
+	disks := map[string]bool{}
+	for _, d := range spec.Disks {
+		if err := devicePathValidation(d.Device); err != nil {
+			return err
+		}
+		if disks[d.Device] {
+			return fmt.Errorf("disk %s is partitioned twice", d.Device)
+		}
+		var total int32
+		for _, size := range d.Layout {
+			if size <= 0 {
+				return fmt.Errorf("invalid partition size %d of disk %s", size, d.Device)
+			}
+			total += size
+		}
+		if total > 100 {
+			return fmt.Errorf("the partitions of disk %s take %d%% of it", d.Device, total)
+		}
+		disks[d.Device] = true
+	}
+
+	// Devices used as physical volumes, or their disks, cannot take a
+	// partition table or a filesystem
+	pvs := map[string]string{}
+	logicalVolumes := map[string]bool{}
+	for _, vg := range spec.VolumeGroups {
+		for _, pv := range vg.PhysicalVolumes {
+			if err := devicePathValidation(pv); err != nil {
+				return err
+			}
+			if other, ok := pvs[pv]; ok {
+				return fmt.Errorf("device %s is a physical volume of both volume groups %s and %s", pv, other, vg.Name)
+			}
+			if disk := diskOf(pv, disks); disk != "" {
+				return fmt.Errorf("device %s of volume group %s overlaps disk %s", pv, vg.Name, disk)
+			}
+			pvs[pv] = vg.Name
+		}
+		for _, lv := range vg.LogicalVolumes {
+			path := "/dev/" + vg.Name + "/" + lv.Name
+			if logicalVolumes[path] {
+				return fmt.Errorf("duplicate logical volume %s", path)
+			}
+			logicalVolumes[path] = true
+		}
+	}
+
+	filesystems := map[string]bool{}
+	for _, f := range spec.Filesystems {
+		if err := devicePathValidation(f.Device); err != nil {
+			return err
+		}
+		device := f.Device
+		switch f.Partition {
+		case "", "none":
+			// The filesystem takes the whole device
+			if disks[device] {
+				return fmt.Errorf("device %s has a filesystem on the whole disk and is partitioned by disks", device)
+			}
+		case "auto", "any":
+		default:
+			if _, err := strconv.Atoi(f.Partition); err != nil {
+				return fmt.Errorf("invalid partition %q of filesystem on %s. use a number, auto, any or none", f.Partition, device)
+			}
+			device = partitionPath(device, f.Partition)
+		}
+		if filesystems[device] {
+			return fmt.Errorf("device %s has more than one filesystem", device)
+		}
+		for pv, vg := range pvs {
+			switch {
+			case device == pv:
+				return fmt.Errorf("device %s has a filesystem and is a physical volume of volume group %s", device, vg)
+			case diskOf(device, map[string]bool{pv: true}) != "":
+				return fmt.Errorf("device %s has a filesystem and is a partition of physical volume %s of volume group %s", device, pv, vg)
+			case diskOf(pv, map[string]bool{device: true}) != "":
+				return fmt.Errorf("device %s has a filesystem and holds a physical volume of volume group %s", device, vg)
+			}
+		}
+		filesystems[device] = true
+	}
+
+	mountPoints := map[string]bool{}
+	for _, m := range spec.Mounts {
+		if m.Device == "" {
+			return fmt.Errorf("device of mount point %s not set", m.MountPoint)
+		}
+		mountPoint := path.Clean(m.MountPoint)
+		if !path.IsAbs(mountPoint) {
+			return fmt.Errorf("invalid mount point %q. use an absolute path", m.MountPoint)
+		}
+		if mountPoints[mountPoint] {
+			return fmt.Errorf("duplicate mount point %s", mountPoint)
+		}
+		mountPoints[mountPoint] = true
+	}
+	return nil
+}
+
+// packagesValidation checks that the package repositories are unique and
+// get their keys from one place.
+func (r *NodeConfig) packagesValidation() error {
+	packages := r.Spec.Packages
+	if packages == nil {
+		return nil
+	}
+	if r.Format() == Ignition {
+		return fmt.Errorf("packages is not supported with the %s format", Ignition)
+	}
+
+	for _, p := range packages.Packages {
+		if strings.TrimSpace(p) == "" {
+			return fmt.Errorf("empty package name")
+		}
+	}
+	repos := map[string]bool{}
+	for _, repo := range packages.YumRepos {
+		if repos[repo.ID] {
+			return fmt.Errorf("duplicate yum repository %q", repo.ID)
+		}
+		repos[repo.ID] = true
+		if u, err := url.Parse(repo.BaseURL); err != nil || u.Scheme == "" {
+			return fmt.Errorf("invalid baseURL %q of yum repository %s", repo.BaseURL, repo.ID)
+		}
+		if repo.GPGKey != "" && repo.GPGKeyRef != nil {
+			return fmt.Errorf("yum repository %s sets both gpgKey and gpgKeyRef. set only one of them", repo.ID)
+		}
+	}
+	sources := map[string]bool{}
+	for _, source := range packages.AptSources {
+		if sources[source.Name] {
+			return fmt.Errorf("duplicate apt source %q", source.Name)
+		}
+		sources[source.Name] = true
+		if strings.TrimSpace(source.Source) == "" {
+			return fmt.Errorf("source of apt source %s not set", source.Name)
+		}
+		if source.KeyID != "" && source.KeyRef != nil {
+			return fmt.Errorf("apt source %s sets both keyID and keyRef. set only one of them", source.Name)
+		}
+		if source.KeyServer != "" && source.KeyID == "" {
+			return fmt.Errorf("apt source %s sets keyServer without keyID", source.Name)
+		}
+	}
+	return nil
+}
+
+// devicePathValidation checks that a device is given by its /dev path.
+func devicePathValidation(device string) error {
+	if !strings.HasPrefix(device, "/dev/") || strings.ContainsAny(device, " \t\n") {
+		return fmt.Errorf("invalid device %q. use a device path such as /dev/sdb", device)
+	}
+	return nil
+}
+
+// partitionPath returns the path of the numbered partition of the disk. The
+// kernel puts a "p" between a disk name ending in a digit and the number,
+// as in /dev/nvme0n1p1 or /dev/mmcblk0p1.
+func partitionPath(disk, partition string) string {
+	if last := disk[len(disk)-1]; last >= '0' && last <= '9' {
+		return disk + "p" + partition
+	}
+	return disk + partition
+}
+
+// diskOf returns the disk among the given ones that device is or is a
+// partition of, such as /dev/sdb for /dev/sdb1 or /dev/nvme0n1 for
+// /dev/nvme0n1p1.
+func diskOf(device string, disks map[string]bool) string {
+	for disk := range disks {
+		if device == disk {
+			return disk
+		}
+		if strings.HasPrefix(device, disk) && partitionRegexp.MatchString(strings.TrimPrefix(device, disk)) {
+			return disk
+		}
+	}
+	return ""
+}
+
+func rootDeviceHintsValidation(name string, hints *RootDeviceHints) error {
+	if hints == nil {
+		return nil
+	}
+	if hints.DeviceName != "" && !strings.HasPrefix(hints.DeviceName, "/dev/") {
+		return fmt.Errorf("invalid deviceName %q of %s. use a device path such as /dev/sda", hints.DeviceName, name)
+	}
+	if hints.HCTL != "" && !hctlRegexp.MatchString(hints.HCTL) {
+		return fmt.Errorf("invalid hctl %q of %s. use the host:channel:target:lun form such as 0:0:0:0", hints.HCTL, name)
+	}
+	if *hints == (RootDeviceHints{}) {
+		return fmt.Errorf("no hints set in %s", name)
+	}
+	return nil
+}
+
+func interfaceValidation(name string, config InterfaceConfig) error {
+	for _, a := range config.Addresses {
+		if _, _, err := net.ParseCIDR(a); err != nil {
+			return fmt.Errorf("invalid address %q of interface %s. use the CIDR notation", a, name)
+		}
+	}
+	for _, gw := range []string{config.Gateway4, config.Gateway6} {
+		if gw != "" && net.ParseIP(gw) == nil {
+			return fmt.Errorf("invalid gateway %q of interface %s", gw, name)
+		}
+	}
+	if config.Nameservers != nil {
+		for _, ns := range config.Nameservers.Addresses {
+			if net.ParseIP(ns) == nil {
+				return fmt.Errorf("invalid nameserver %q of interface %s", ns, name)
+			}
+		}
+	}
+	for _, route := range config.Routes {
+		if _, _, err := net.ParseCIDR(route.To); err != nil && route.To != "default" {
+			return fmt.Errorf("invalid route destination %q of interface %s. use the CIDR notation", route.To, name)
+		}
+		if net.ParseIP(route.Via) == nil {
+			return fmt.Errorf("invalid route gateway %q of interface %s", route.Via, name)
+		}
+	}
+	return nil
+}
+
 func (r *NodeConfig) bmcValidation(bmcInfo *BMC) error {
 	bmcAddr := bmcInfo.Address  // "192.168.111.204"
 	bmcUser := bmcInfo.Username // "USERID"
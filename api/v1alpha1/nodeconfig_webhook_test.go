@@ -20,7 +20,10 @@ import (
 	"strings"
 	"testing"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kubeadmv1beta2 "github.com/tmax-cloud/nodeconfig-operator/types/v1beta2"
 )
 
 func errorContains(out error, want string) bool {
@@ -60,6 +63,562 @@ func TestNodeConfigCreate(t *testing.T) {
 			}},
 			wantedErr: "",
 		},
+		{
+			name: "both credentialsSecretRef and inline credentials",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+					Username:             "USERID",
+					Password:             "PASSW0RD",
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+			}},
+			wantedErr: "mutually exclusive",
+		},
+		{
+			name: "no credentials",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address: "192.168.111.204",
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+			}},
+			wantedErr: "BMC credentials not set",
+		},
+		{
+			name: "controlPlaneJoin without joinConfiguration",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Role: ControlPlaneJoinRole,
+			}},
+			wantedErr: "kubeadm.joinConfiguration must be set",
+		},
+		{
+			name: "controlPlaneInit with joinConfiguration",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Role: ControlPlaneInitRole,
+				Kubeadm: &KubeadmSpec{
+					JoinConfiguration: &kubeadmv1beta2.JoinConfiguration{},
+				},
+			}},
+			wantedErr: "kubeadm.joinConfiguration cannot be set",
+		},
+		{
+			name: "unsupported kubernetesVersion",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				KubernetesVersion: "v1.12.10",
+			}},
+			wantedErr: "invalid kubernetesVersion",
+		},
+		{
+			name: "bond of an unknown ethernet",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Network: &Network{
+					Ethernets: []Ethernet{{Name: "eno1", MACAddress: "00:5c:52:31:3a:9c"}},
+					Bonds:     []Bond{{Name: "bond0", Interfaces: []string{"eno1", "eno2"}}},
+				},
+			}},
+			wantedErr: "bond bond0 refers to unknown ethernet",
+		},
+		{
+			name: "address without prefix length",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Network: &Network{
+					Ethernets: []Ethernet{{
+						Name:            "eno1",
+						MACAddress:      "00:5c:52:31:3a:9c",
+						InterfaceConfig: InterfaceConfig{Addresses: []string{"192.168.0.10"}},
+					}},
+				},
+			}},
+			wantedErr: "invalid address",
+		},
+		{
+			name: "unterminated hostname template",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Metadata: &Metadata{Hostname: "worker-{{ .Index"},
+			}},
+			wantedErr: "invalid metadata hostname",
+		},
+		{
+			name: "instance-id metadata key",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Metadata: &Metadata{Keys: map[string]string{"instance-id": "abc"}},
+			}},
+			wantedErr: "metadata key \"instance-id\" is set from the NodeConfig",
+		},
+		{
+			name: "root device name without /dev",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Storage: &Storage{RootDeviceHints: &RootDeviceHints{DeviceName: "sda"}},
+			}},
+			wantedErr: "invalid deviceName",
+		},
+		{
+			name: "software RAID root volume of level 0",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				RAID: &RAID{SoftwareRAIDVolumes: []SoftwareRAIDVolume{{Level: "0"}}},
+			}},
+			wantedErr: "must be of level 1",
+		},
+		{
+			name: "physical volume on a partitioned disk",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Disks: []Disk{{Device: "/dev/sdb"}},
+				VolumeGroups: []VolumeGroup{{
+					Name:            "data",
+					PhysicalVolumes: []string{"/dev/sdb1"},
+				}},
+			}},
+			wantedErr: "overlaps disk /dev/sdb",
+		},
+		{
+			name: "filesystem on an NVMe partition of a physical volume",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Disks: []Disk{{Device: "/dev/nvme0n1", Layout: []int32{50, 50}}},
+				VolumeGroups: []VolumeGroup{{
+					Name:            "data",
+					PhysicalVolumes: []string{"/dev/nvme1n1p1"},
+				}},
+				Filesystems: []Filesystem{{Device: "/dev/nvme1n1", Partition: "1", Filesystem: "xfs"}},
+			}},
+			wantedErr: "device /dev/nvme1n1p1 has a filesystem and is a physical volume of volume group data",
+		},
+		{
+			name: "filesystems on NVMe partitions",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Disks: []Disk{{Device: "/dev/nvme0n1", Layout: []int32{50, 50}}},
+				Filesystems: []Filesystem{
+					{Device: "/dev/nvme0n1", Partition: "1", Filesystem: "xfs"},
+					{Device: "/dev/nvme0n1p2", Filesystem: "ext4"},
+				},
+			}},
+			wantedErr: "",
+		},
+		{
+			name: "filesystem on a partitioned whole disk",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Disks:       []Disk{{Device: "/dev/sdb", Layout: []int32{50, 50}}},
+				Filesystems: []Filesystem{{Device: "/dev/sdb", Partition: "none", Filesystem: "xfs"}},
+			}},
+			wantedErr: "device /dev/sdb has a filesystem on the whole disk and is partitioned by disks",
+		},
+		{
+			name: "duplicate mount point",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Mounts: []Mount{
+					{Device: "/dev/sdb1", MountPoint: "/var/lib/etcd"},
+					{Device: "/dev/sdc1", MountPoint: "/var/lib/etcd/"},
+				},
+			}},
+			wantedErr: "duplicate mount point /var/lib/etcd",
+		},
+		{
+			name: "yum repository with two GPG keys",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Packages: &Packages{
+					YumRepos: []YumRepo{{
+						ID:        "kubernetes",
+						BaseURL:   "https://packages.cloud.google.com/yum/repos/kubernetes-el7-x86_64",
+						GPGKey:    "https://packages.cloud.google.com/yum/doc/rpm-package-key.gpg",
+						GPGKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "keys"}, Key: "kubernetes"},
+					}},
+				},
+			}},
+			wantedErr: "sets both gpgKey and gpgKeyRef",
+		},
+		{
+			name: "file content from both a secret and a configmap",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL:      "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+					Checksum: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2.md5sum",
+				},
+				Files: []File{{
+					Path: "/etc/kubernetes/pki/ca.crt",
+					ContentFrom: &FileSource{
+						Secret:    &FileSourceKey{Name: "ca", Key: "tls.crt"},
+						ConfigMap: &FileSourceKey{Name: "ca", Key: "ca.crt"},
+					},
+				}},
+			}},
+			wantedErr: "must set exactly one of secret and configMap",
+		},
+		{
+			name: "image left to a profile",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				ProfileRef: &corev1.LocalObjectReference{Name: "workers"},
+			}},
+			wantedErr: "",
+		},
+		{
+			name: "image without checksum",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				Image: &Image{
+					URL: "http://192.168.111.1:6180/images/CENTOS_8.2_NODE_IMAGE_K8S_v1.20.2.qcow2",
+				},
+			}},
+			wantedErr: "image value not set",
+		},
+		{
+			name: "host selected by labels",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				HostSelector: &HostSelector{
+					MatchLabels:      map[string]string{"rack": "1"},
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "gpu", Operator: metav1.LabelSelectorOpDoesNotExist}},
+				},
+			}},
+			wantedErr: "",
+		},
+		{
+			name: "both bmc and hostSelector",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				BMC: &BMC{
+					Address:              "192.168.111.204",
+					CredentialsSecretRef: &corev1.LocalObjectReference{Name: "test-bmc-secret"},
+				},
+				HostSelector: &HostSelector{MatchLabels: map[string]string{"rack": "1"}},
+			}},
+			wantedErr: "bmc and hostSelector are mutually exclusive",
+		},
+		{
+			name: "invalid hostSelector operator",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				HostSelector: &HostSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "rack", Operator: "Equals", Values: []string{"1"}}},
+				},
+			}},
+			wantedErr: "invalid hostSelector",
+		},
+		{
+			name: "hostSelector with the Delete deletion policy",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				HostSelector:   &HostSelector{MatchLabels: map[string]string{"rack": "1"}},
+				DeletionPolicy: DeletionDelete,
+			}},
+			wantedErr: "deletionPolicy Delete is not allowed with hostSelector",
+		},
+		{
+			name: "hostSelector with the Deprovision deletion policy",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				HostSelector:          &HostSelector{MatchLabels: map[string]string{"rack": "1"}},
+				DeletionPolicy:        DeletionDeprovision,
+				UnavailableHostPolicy: UnavailableHostWait,
+			}},
+			wantedErr: "",
+		},
+		{
+			name: "hostSelector with the Recreate unavailable host policy",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				HostSelector:          &HostSelector{MatchLabels: map[string]string{"rack": "1"}},
+				UnavailableHostPolicy: UnavailableHostRecreate,
+			}},
+			wantedErr: "unavailableHostPolicy Recreate is not allowed with hostSelector",
+		},
+		{
+			name: "hostSelector with the Delete unavailable host policy",
+			nc: &NodeConfig{TypeMeta: metav1.TypeMeta{
+				Kind:       "NodeConfig",
+				APIVersion: "bootstrap.tmax.io/v1alpha1",
+			}, ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test-namespace",
+			}, Spec: NodeConfigSpec{
+				HostSelector:          &HostSelector{MatchLabels: map[string]string{"rack": "1"}},
+				UnavailableHostPolicy: UnavailableHostDelete,
+			}},
+			wantedErr: "unavailableHostPolicy Delete is not allowed with hostSelector",
+		},
 	}
 
 	for _, tt := range tests {
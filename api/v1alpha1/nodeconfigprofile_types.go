@@ -0,0 +1,83 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeConfigProfileSpec defines the desired state of NodeConfigProfile
+type NodeConfigProfileSpec struct {
+	// Selector selects the NodeConfigs the profile applies to by their
+	// labels. An empty selector selects every NodeConfig. A NodeConfig with
+	// a profileRef only gets the profile it refers to.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Image is the image of the NodeConfigs that do not set one
+	// +optional
+	Image *Image `json:"image,omitempty"`
+
+	// Files specifies the files written before the files of the
+	// NodeConfig. A file of the NodeConfig with the same path replaces the
+	// one of the profile. A contentFrom is read from the NodeConfig
+	// namespace.
+	// +optional
+	Files []File `json:"files,omitempty"`
+
+	// Users specifies the users added along with the users of the
+	// NodeConfig. A user of the NodeConfig with the same name replaces the
+	// one of the profile.
+	// +optional
+	Users []User `json:"users,omitempty"`
+
+	// CloudInitCommands specifies the commands run before the commands of
+	// the NodeConfig
+	// +optional
+	CloudInitCommands []string `json:"cloudInitCommands,omitempty"`
+
+	// NTP is the NTP configuration of the NodeConfigs that do not set one
+	// +optional
+	NTP *NTP `json:"ntp,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// NodeConfigProfile is the Schema for the nodeconfigprofiles API. It holds
+// the defaults shared by many NodeConfigs, merged into them when their user
+// data is rendered.
+type NodeConfigProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NodeConfigProfileSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NodeConfigProfileList contains a list of NodeConfigProfile
+type NodeConfigProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeConfigProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeConfigProfile{}, &NodeConfigProfileList{})
+}
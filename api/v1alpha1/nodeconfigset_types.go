@@ -0,0 +1,124 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+const (
+	// NodeConfigSetLabel holds the name of the NodeConfigSet a NodeConfig
+	// was created by.
+	NodeConfigSetLabel = "bootstrap.tmax.io/nodeconfigset"
+
+	// DefaultNodeConfigSetNameTemplate is the name template of the
+	// NodeConfigs of a NodeConfigSet that does not set one.
+	DefaultNodeConfigSetNameTemplate = "{{ .Name }}-{{ .Index }}"
+)
+
+// NodeConfigSetSpec defines the desired state of NodeConfigSet
+type NodeConfigSetSpec struct {
+	// TemplateRef refers to the NodeConfigTemplate, in the namespace of the
+	// set, the NodeConfigs are created from.
+	TemplateRef corev1.LocalObjectReference `json:"templateRef"`
+
+	// NameTemplate is the name of the NodeConfigs. It is a Go template,
+	// given .Name and .Namespace of the set and .Index of the host.
+	// Defaults to {{ .Name }}-{{ .Index }}
+	// +optional
+	NameTemplate string `json:"nameTemplate,omitempty"`
+
+	// CredentialsSecretRef references the Secret holding the BMC
+	// credentials of the hosts that set none.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+
+	// Hosts specifies the BMC of every host. A NodeConfig is created for
+	// each of them.
+	// +optional
+	Hosts []BMC `json:"hosts,omitempty"`
+
+	// InventoryRef refers to a key of a ConfigMap holding more hosts as a
+	// YAML list, in the format of hosts.
+	// +optional
+	InventoryRef *corev1.ConfigMapKeySelector `json:"inventoryRef,omitempty"`
+}
+
+// NodeConfigSetStatus defines the observed state of NodeConfigSet
+type NodeConfigSetStatus struct {
+	// Replicas is the number of NodeConfigs of the set
+	// +optional
+	Replicas int32 `json:"replicas"`
+
+	// ReadyReplicas is the number of NodeConfigs that are ready
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// ProvisionedReplicas is the number of NodeConfigs whose host is
+	// provisioned
+	// +optional
+	ProvisionedReplicas int32 `json:"provisionedReplicas"`
+
+	// ObservedGeneration is the latest generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions defines current service state of the NodeConfigSet.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".status.replicas",description="NodeConfigs of the set"
+//+kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas",description="NodeConfigs that are ready"
+//+kubebuilder:printcolumn:name="Provisioned",type="integer",JSONPath=".status.provisionedReplicas",description="NodeConfigs whose host is provisioned"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// NodeConfigSet is the Schema for the nodeconfigsets API
+type NodeConfigSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeConfigSetSpec   `json:"spec,omitempty"`
+	Status NodeConfigSetStatus `json:"status,omitempty"`
+}
+
+// GetConditions returns the list of conditions for a NodeConfigSet API object.
+func (s *NodeConfigSet) GetConditions() clusterv1.Conditions {
+	return s.Status.Conditions
+}
+
+// SetConditions will set the given conditions on a NodeConfigSet object.
+func (s *NodeConfigSet) SetConditions(conditions clusterv1.Conditions) {
+	s.Status.Conditions = conditions
+}
+
+//+kubebuilder:object:root=true
+
+// NodeConfigSetList contains a list of NodeConfigSet
+type NodeConfigSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeConfigSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeConfigSet{}, &NodeConfigSetList{})
+}
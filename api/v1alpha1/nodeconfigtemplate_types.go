@@ -0,0 +1,64 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+)
+
+// NodeConfigTemplateSpec defines the desired state of NodeConfigTemplate
+type NodeConfigTemplateSpec struct {
+	// Template describes the NodeConfigs created from the template
+	Template NodeConfigTemplateResource `json:"template"`
+}
+
+// NodeConfigTemplateResource describes the NodeConfigs created from a
+// NodeConfigTemplate.
+type NodeConfigTemplateResource struct {
+	// ObjectMeta holds the labels and annotations of the NodeConfigs
+	// +optional
+	ObjectMeta clusterv1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec is the spec of the NodeConfigs. Its bmc is left out and set
+	// for every host by the NodeConfigSet.
+	Spec NodeConfigSpec `json:"spec"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// NodeConfigTemplate is the Schema for the nodeconfigtemplates API
+type NodeConfigTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NodeConfigTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NodeConfigTemplateList contains a list of NodeConfigTemplate
+type NodeConfigTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeConfigTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeConfigTemplate{}, &NodeConfigTemplateList{})
+}
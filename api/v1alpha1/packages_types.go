@@ -0,0 +1,109 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Packages defines the package repositories and the packages installed on
+// first boot. It is rendered to the cloud-init yum_repos, apt and
+// package_update_upgrade_install modules.
+type Packages struct {
+	// Update refreshes the package database on first boot
+	// +optional
+	Update bool `json:"update,omitempty"`
+
+	// Upgrade upgrades the installed packages on first boot
+	// +optional
+	Upgrade bool `json:"upgrade,omitempty"`
+
+	// Packages specifies the packages to install, optionally pinned as
+	// name=version
+	// +optional
+	Packages []string `json:"packages,omitempty"`
+
+	// YumRepos specifies the yum repositories to add on RPM based images
+	// +optional
+	YumRepos []YumRepo `json:"yumRepos,omitempty"`
+
+	// AptSources specifies the apt sources to add on Debian based images
+	// +optional
+	AptSources []AptSource `json:"aptSources,omitempty"`
+}
+
+// YumRepo defines a yum repository, written to
+// /etc/yum.repos.d/<id>.repo.
+type YumRepo struct {
+	// ID is the repository id
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9._:-]+$`
+	ID string `json:"id"`
+
+	// Name is the human readable name of the repository. Defaults to the
+	// id.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// BaseURL is the URL of the repository
+	BaseURL string `json:"baseURL"`
+
+	// Enabled enables the repository. Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// GPGCheck verifies the signature of the packages. Defaults to true when
+	// a GPG key is set.
+	// +optional
+	GPGCheck *bool `json:"gpgCheck,omitempty"`
+
+	// GPGKey is the URL of the GPG key of the repository
+	// +optional
+	GPGKey string `json:"gpgKey,omitempty"`
+
+	// GPGKeyRef selects the ASCII armored GPG key of the repository from a
+	// ConfigMap in the NodeConfig namespace. It is written to
+	// /etc/pki/rpm-gpg/RPM-GPG-KEY-<id>.
+	// +optional
+	GPGKeyRef *corev1.ConfigMapKeySelector `json:"gpgKeyRef,omitempty"`
+}
+
+// AptSource defines an apt source, written to
+// /etc/apt/sources.list.d/<name>.list.
+type AptSource struct {
+	// Name is the name of the source
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9._-]+$`
+	Name string `json:"name"`
+
+	// Source is the sources.list line, e.g.
+	// deb http://apt.kubernetes.io/ kubernetes-xenial main. $RELEASE is
+	// replaced with the release codename of the image.
+	Source string `json:"source"`
+
+	// KeyID is the id of the signing key, fetched from KeyServer
+	// +optional
+	KeyID string `json:"keyID,omitempty"`
+
+	// KeyServer is the key server KeyID is fetched from. Defaults to
+	// keyserver.ubuntu.com.
+	// +optional
+	KeyServer string `json:"keyServer,omitempty"`
+
+	// KeyRef selects the ASCII armored signing key from a ConfigMap in the
+	// NodeConfig namespace
+	// +optional
+	KeyRef *corev1.ConfigMapKeySelector `json:"keyRef,omitempty"`
+}
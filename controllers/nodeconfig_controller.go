@@ -18,8 +18,10 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-logr/logr"
+	bmhv1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	"github.com/pkg/errors"
 
 	"github.com/tmax-cloud/nodeconfig-operator/util"
@@ -27,10 +29,18 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
 )
@@ -40,6 +50,7 @@ type NodeConfigReconciler struct {
 	Client        client.Client
 	ConfigManager util.ConfigManager
 	Log           logr.Logger
+	Recorder      record.EventRecorder
 	Scheme        *runtime.Scheme
 }
 
@@ -53,13 +64,234 @@ type Scope struct {
 func (r *NodeConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&bootstrapv1.NodeConfig{}).
+		Watches(
+			&source.Kind{Type: &bmhv1.BareMetalHost{}},
+			handler.EnqueueRequestsFromMapFunc(r.BareMetalHostToNodeConfig),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.SecretToNodeConfigs),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.ConfigMapToNodeConfigs),
+		).
+		Watches(
+			&source.Kind{Type: &bootstrapv1.NodeConfigProfile{}},
+			handler.EnqueueRequestsFromMapFunc(r.NodeConfigProfileToNodeConfigs),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(r.NodeToNodeConfigs),
+		).
 		Complete(r)
 }
 
+// BareMetalHostToNodeConfig maps a BareMetalHost event to the NodeConfig
+// consuming it, or else sharing its name, so the NodeConfig follows the host
+// state. A host that can be claimed is also mapped to the NodeConfigs whose
+// host selector matches it and that have no host yet.
+func (r *NodeConfigReconciler) BareMetalHostToNodeConfig(o client.Object) []reconcile.Request {
+	host, ok := o.(*bmhv1.BareMetalHost)
+	if !ok {
+		return nil
+	}
+	if ref := host.Spec.ConsumerRef; ref != nil && ref.Kind == "NodeConfig" {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{
+			Namespace: ref.Namespace,
+			Name:      ref.Name,
+		}}}
+	}
+	result := []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: host.Namespace,
+		Name:      host.Name,
+	}}}
+
+	configList := &bootstrapv1.NodeConfigList{}
+	if err := r.Client.List(context.TODO(), configList, client.InNamespace(host.Namespace)); err != nil {
+		return result
+	}
+	for i := range configList.Items {
+		config := &configList.Items[i]
+		selector := config.Spec.HostSelector
+		if selector == nil || config.Status.HostRef != nil || config.Name == host.Name {
+			continue
+		}
+		if !util.HostSelects(selector, host) || !util.HostClaimable(host, selector) {
+			continue
+		}
+		result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(config)})
+	}
+	return result
+}
+
+// SecretToNodeConfigs maps a Secret event to the NodeConfigs using it as
+// their BMC credentials, their certificate key or the content of a file of
+// theirs or of their NodeConfigProfile.
+func (r *NodeConfigReconciler) SecretToNodeConfigs(o client.Object) []reconcile.Request {
+	secret, ok := o.(*corev1.Secret)
+	if !ok {
+		return nil
+	}
+
+	configList := &bootstrapv1.NodeConfigList{}
+	if err := r.Client.List(context.TODO(), configList, client.InNamespace(secret.Namespace)); err != nil {
+		return nil
+	}
+
+	profileFiles := r.profileFiles(context.TODO())
+
+	var result []reconcile.Request
+	for i := range configList.Items {
+		config := &configList.Items[i]
+		usesSecret := config.Spec.BMC != nil && config.BMCCredentialsName() == secret.Name
+		if kubeadm := config.Spec.Kubeadm; kubeadm != nil && kubeadm.CertificateKeySecretRef != nil {
+			usesSecret = usesSecret || kubeadm.CertificateKeySecretRef.Name == secret.Name
+		}
+		for _, f := range configFiles(config, profileFiles) {
+			usesSecret = usesSecret || (f.ContentFrom != nil && f.ContentFrom.Secret != nil && f.ContentFrom.Secret.Name == secret.Name)
+		}
+		if !usesSecret {
+			continue
+		}
+		result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(config)})
+	}
+	return result
+}
+
+// ConfigMapToNodeConfigs maps a ConfigMap event to the NodeConfigs reading
+// the content of a file of theirs or of their NodeConfigProfile, or the GPG
+// keys of their package repositories from it.
+func (r *NodeConfigReconciler) ConfigMapToNodeConfigs(o client.Object) []reconcile.Request {
+	configMap, ok := o.(*corev1.ConfigMap)
+	if !ok {
+		return nil
+	}
+
+	configList := &bootstrapv1.NodeConfigList{}
+	if err := r.Client.List(context.TODO(), configList, client.InNamespace(configMap.Namespace)); err != nil {
+		return nil
+	}
+
+	profileFiles := r.profileFiles(context.TODO())
+
+	var result []reconcile.Request
+	for i := range configList.Items {
+		config := &configList.Items[i]
+		usesConfigMap := false
+		for _, f := range configFiles(config, profileFiles) {
+			usesConfigMap = usesConfigMap || (f.ContentFrom != nil && f.ContentFrom.ConfigMap != nil && f.ContentFrom.ConfigMap.Name == configMap.Name)
+		}
+		if packages := config.Spec.Packages; packages != nil {
+			for _, repo := range packages.YumRepos {
+				usesConfigMap = usesConfigMap || (repo.GPGKeyRef != nil && repo.GPGKeyRef.Name == configMap.Name)
+			}
+			for _, source := range packages.AptSources {
+				usesConfigMap = usesConfigMap || (source.KeyRef != nil && source.KeyRef.Name == configMap.Name)
+			}
+		}
+		if !usesConfigMap {
+			continue
+		}
+		result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(config)})
+	}
+	return result
+}
+
+// profileFiles returns the files of every NodeConfigProfile by profile name,
+// or nil when the profiles cannot be listed.
+func (r *NodeConfigReconciler) profileFiles(ctx context.Context) map[string][]bootstrapv1.File {
+	profiles := &bootstrapv1.NodeConfigProfileList{}
+	if err := r.Client.List(ctx, profiles); err != nil {
+		return nil
+	}
+	files := map[string][]bootstrapv1.File{}
+	for _, profile := range profiles.Items {
+		files[profile.Name] = profile.Spec.Files
+	}
+	return files
+}
+
+// configFiles returns the files of the NodeConfig followed by those of the
+// profile it refers to and of the profile last applied to it. The profile
+// files are only merged into the spec while rendering, so they are looked up
+// by name.
+func configFiles(config *bootstrapv1.NodeConfig, profileFiles map[string][]bootstrapv1.File) []bootstrapv1.File {
+	files := config.Spec.Files
+	if ref := config.Spec.ProfileRef; ref != nil {
+		files = append(files[:len(files):len(files)], profileFiles[ref.Name]...)
+	}
+	if applied := config.Status.AppliedProfile; applied != nil {
+		files = append(files[:len(files):len(files)], profileFiles[applied.Name]...)
+	}
+	return files
+}
+
+// NodeConfigProfileToNodeConfigs maps a NodeConfigProfile event to the
+// NodeConfigs referring to it or selected by it, and to those it was applied
+// to before, so they are rendered again.
+func (r *NodeConfigReconciler) NodeConfigProfileToNodeConfigs(o client.Object) []reconcile.Request {
+	profile, ok := o.(*bootstrapv1.NodeConfigProfile)
+	if !ok {
+		return nil
+	}
+
+	configList := &bootstrapv1.NodeConfigList{}
+	if err := r.Client.List(context.TODO(), configList); err != nil {
+		return nil
+	}
+
+	var result []reconcile.Request
+	for i := range configList.Items {
+		config := &configList.Items[i]
+		usesProfile := config.Status.AppliedProfile != nil && config.Status.AppliedProfile.Name == profile.Name
+		if ref := config.Spec.ProfileRef; ref != nil {
+			usesProfile = usesProfile || ref.Name == profile.Name
+		} else if selected, err := util.ProfileSelects(profile, config); err == nil {
+			usesProfile = usesProfile || selected
+		}
+		if !usesProfile {
+			continue
+		}
+		result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(config)})
+	}
+	return result
+}
+
+// NodeToNodeConfigs maps a Node event to the NodeConfigs waiting for the node
+// to register, so their bootstrap token is revoked.
+func (r *NodeConfigReconciler) NodeToNodeConfigs(o client.Object) []reconcile.Request {
+	node, ok := o.(*corev1.Node)
+	if !ok {
+		return nil
+	}
+
+	configList := &bootstrapv1.NodeConfigList{}
+	if err := r.Client.List(context.TODO(), configList); err != nil {
+		return nil
+	}
+
+	var result []reconcile.Request
+	for i := range configList.Items {
+		config := &configList.Items[i]
+		if config.Status.BootstrapTokenID == "" || util.NodeName(config) != node.Name {
+			continue
+		}
+		result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(config)})
+	}
+	return result
+}
+
 //+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=nodeconfigs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=nodeconfigs/status,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=nodeconfigs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=nodeconfigprofiles,verbs=get;list;watch
+//+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=ippools,verbs=get;list;watch
+//+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=ippools/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=ipclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=ipclaims/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups="",resources=secrets;events;configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
 
 // Add RBAC rules to access cluster-api resources
 //+kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts,verbs=get;list;watch;create;update;patch;delete
@@ -75,7 +307,7 @@ func (r *NodeConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.8.3/pkg/reconcile
-func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, rerr error) {
 	log := ctrllog.FromContext(ctx)
 	log.Info("Start nodeconfig operator reconcile")
 
@@ -91,12 +323,9 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// Do nothing if the state of NC is already 'ready'
-	// log.Info("NC status check", "NC.Status.Ready", config.Status.Ready)
-	if config.Status.Ready {
-		log.Info("The work related to NodeConfig has already completed", "config name", config.Name)
-		return ctrl.Result{}, nil
-	}
+	// The NodeConfigProfile is merged into the spec for rendering only. The
+	// spec is put back before the NodeConfig is patched.
+	spec := config.Spec.DeepCopy()
 
 	// Create a helper for managing the baremetal container hosting the machine.
 	configMgr, err := r.ConfigManager.NewConfigManager(r.Client, config, log)
@@ -111,16 +340,60 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 	// Always patch nodeconfig exiting this function so we can persist any nodeconfig changes.
 	defer func() {
-		if err := patchHelper.Patch(ctx, configMgr.NodeConfig); err != nil {
+		var patchOpts []patch.Option
+		if rerr == nil {
+			patchOpts = append(patchOpts, patch.WithStatusObservedGeneration{})
+		}
+		configMgr.NodeConfig.Spec = *spec
+		if err := patchNodeConfig(ctx, patchHelper, configMgr.NodeConfig, patchOpts...); err != nil {
 			log.Info("failed to Patch nodeconfig")
+			if rerr == nil {
+				rerr = err
+			}
 		}
 		log.Info("End nodeconfig operator reconcile", "NodeConfig.Status", configMgr.NodeConfig.Status)
 	}()
 
-	// Create CloudInit data as nodeinitconfig
+	// Handle deleted nodeconfigs
+	if !config.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, configMgr)
+	}
+
+	// Add the finalizer first so the host is released whatever happens next
+	if !controllerutil.ContainsFinalizer(config, bootstrapv1.NodeConfigFinalizer) {
+		controllerutil.AddFinalizer(config, bootstrapv1.NodeConfigFinalizer)
+		return ctrl.Result{}, nil
+	}
+
+	if config.Status.Phase == "" {
+		config.Status.Phase = bootstrapv1.NodeConfigPhasePending
+	}
+
+	// Create CloudInit data as nodeinitconfig. It is rendered on every reconcile
+	// so spec changes reach the user data secret.
+	if config.Status.UserData == nil {
+		config.Status.Phase = bootstrapv1.NodeConfigPhaseRenderingUserData
+	}
+	if err := configMgr.ApplyProfile(ctx); err != nil {
+		conditions.MarkFalse(config, bootstrapv1.UserDataRenderedCondition,
+			bootstrapv1.ProfileApplyFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+		config.Status.Phase = bootstrapv1.NodeConfigPhaseFailed
+		return ctrl.Result{}, err
+	}
+	// The image may be left to a profile, but the host cannot be provisioned
+	// without one. The profile watch brings us back once a profile sets it.
+	if config.Spec.Image == nil {
+		conditions.MarkFalse(config, bootstrapv1.HostAssociatedCondition,
+			bootstrapv1.ImageNotSetReason, clusterv1.ConditionSeverityWarning,
+			"Neither the NodeConfig nor a NodeConfigProfile sets an image")
+		config.Status.Phase = bootstrapv1.NodeConfigPhasePending
+		return ctrl.Result{}, nil
+	}
 	var cloudinitName string
 	if cloudinitName, err = configMgr.CreateNodeInitConfig(ctx); err != nil {
-		configMgr.SetError("Failed to create a cloudinit config")
+		conditions.MarkFalse(config, bootstrapv1.UserDataRenderedCondition,
+			bootstrapv1.UserDataRenderFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+		config.Status.Phase = bootstrapv1.NodeConfigPhaseFailed
 		return ctrl.Result{}, err
 	}
 	// Set secret reference
@@ -128,40 +401,259 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		Name:      cloudinitName,
 		Namespace: config.Namespace,
 	}
+	if config.Status.NetworkData, err = configMgr.CreateNetworkData(ctx); err != nil {
+		conditions.MarkFalse(config, bootstrapv1.UserDataRenderedCondition,
+			bootstrapv1.NetworkDataRenderFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+		config.Status.Phase = bootstrapv1.NodeConfigPhaseFailed
+		return ctrl.Result{}, err
+	}
+	if config.Status.MetaData, err = configMgr.CreateMetaData(ctx); err != nil {
+		conditions.MarkFalse(config, bootstrapv1.UserDataRenderedCondition,
+			bootstrapv1.MetaDataRenderFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+		config.Status.Phase = bootstrapv1.NodeConfigPhaseFailed
+		return ctrl.Result{}, err
+	}
+	conditions.MarkTrue(config, bootstrapv1.UserDataRenderedCondition)
 
-	// Create the BareMetalHost CR
-	if bmh, isAvail := configMgr.FindHost(ctx); bmh == nil {
+	// Create the BareMetalHost CR, or claim a registered one matching the selector
+	bmh, isAvail := configMgr.FindHost(ctx)
+	if bmh == nil && config.Spec.HostSelector != nil {
+		if bmh, err = configMgr.ClaimHost(ctx); err != nil {
+			conditions.MarkFalse(config, bootstrapv1.BareMetalHostCreatedCondition,
+				bootstrapv1.HostClaimFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+			config.Status.Phase = bootstrapv1.NodeConfigPhaseFailed
+			return ctrl.Result{}, err
+		}
+		if bmh == nil {
+			// The host watch brings us back when a matching host becomes available
+			log.Info("No available BMH matches the host selector")
+			conditions.MarkFalse(config, bootstrapv1.BareMetalHostCreatedCondition,
+				bootstrapv1.NoMatchingHostReason, clusterv1.ConditionSeverityWarning,
+				"No available BareMetalHost matches the host selector")
+			config.Status.Phase = bootstrapv1.NodeConfigPhasePending
+			return ctrl.Result{}, nil
+		}
+	} else if bmh == nil {
 		log.Info("The BMH looking for was not found. Now create a BMH")
+		config.Status.Phase = bootstrapv1.NodeConfigPhaseRegistering
 		if err := configMgr.CreateBareMetalHost(ctx); err != nil {
-			configMgr.SetError("Failed to create BareMetalHost")
+			conditions.MarkFalse(config, bootstrapv1.BareMetalHostCreatedCondition,
+				bootstrapv1.BareMetalHostCreateFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+			config.Status.Phase = bootstrapv1.NodeConfigPhaseFailed
 			return ctrl.Result{}, err
 		}
 	} else if !isAvail {
-		configMgr.SetError("The found BareMetalHost is not available. " +
-			"BMH.provisioning.state: " + string(bmh.Status.Provisioning.State))
-		// Delete the NodeConfig
-		log.Info("The found BareMetalHost is not available. Delete the nodeconfig")
+		return r.reconcileUnavailableHost(ctx, config, bmh)
+	}
+	conditions.MarkTrue(config, bootstrapv1.BareMetalHostCreatedCondition)
 
-		if err := r.Client.Delete(ctx, bmh); err != nil {
-			return ctrl.Result{}, errors.Wrapf(err, "Failed to delete the BMH %s/%s", config.Namespace, config.Name)
-		}
-		if err := r.Client.Delete(ctx, config); err != nil {
-			return ctrl.Result{}, errors.Wrapf(err, "Failed to delete the NodeConfig %s/%s", config.Namespace, config.Name)
+	// Check the inspected hardware before the host gets the image and the
+	// user data. A host that already has them is left alone.
+	if requirements := config.Spec.HardwareRequirements; requirements == nil {
+		conditions.Delete(config, bootstrapv1.HardwareRequirementsMetCondition)
+	} else if !configMgr.IsAssociated(bmh) {
+		inspected, err := util.CheckHardwareRequirements(requirements, bmh)
+		switch {
+		case err != nil:
+			conditions.MarkFalse(config, bootstrapv1.HardwareRequirementsMetCondition,
+				bootstrapv1.HardwareRequirementsNotMetReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+			r.Recorder.Eventf(config, corev1.EventTypeWarning, bootstrapv1.HardwareRequirementsNotMetReason,
+				"The BareMetalHost does not meet the hardware requirements: %s", err.Error())
+			config.Status.Phase = bootstrapv1.NodeConfigPhaseFailed
+			// The NodeConfig or host watch brings us back once either changes
+			return ctrl.Result{}, nil
+		case !inspected:
+			conditions.MarkFalse(config, bootstrapv1.HardwareRequirementsMetCondition,
+				bootstrapv1.WaitingForInspectionReason, clusterv1.ConditionSeverityInfo,
+				"The BareMetalHost has not been inspected yet")
+			config.Status.Phase = phaseFromHost(bmh)
+			return ctrl.Result{}, nil
 		}
-		return ctrl.Result{}, nil
+		conditions.MarkTrue(config, bootstrapv1.HardwareRequirementsMetCondition)
 	}
 
 	// Associate the baremetalhost hosting the machine
 	if err = configMgr.Associate(ctx, config); err != nil {
-		configMgr.SetError("Failed to associate the NodeConfig to a BaremetalHost")
+		conditions.MarkFalse(config, bootstrapv1.HostAssociatedCondition,
+			bootstrapv1.AssociateFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+		config.Status.Phase = bootstrapv1.NodeConfigPhaseFailed
 		return ctrl.Result{}, errors.Wrapf(err, "Failed to associate the NodeConfig to a BaremetalHost")
 	}
+	conditions.MarkTrue(config, bootstrapv1.HostAssociatedCondition)
+
+	// Follow the BareMetalHost until it finishes provisioning
+	switch {
+	case bmh == nil:
+		conditions.MarkFalse(config, bootstrapv1.ProvisionedCondition,
+			bootstrapv1.WaitingForProvisioningReason, clusterv1.ConditionSeverityInfo,
+			"The BareMetalHost has just been created")
+	case bmh.Status.OperationalStatus == bmhv1.OperationalStatusError:
+		conditions.MarkFalse(config, bootstrapv1.ProvisionedCondition,
+			bootstrapv1.ProvisioningFailedReason, clusterv1.ConditionSeverityError, "%s", bmh.Status.ErrorMessage)
+	case bmh.Status.Provisioning.State == bmhv1.StateProvisioned ||
+		bmh.Status.Provisioning.State == bmhv1.StateExternallyProvisioned:
+		conditions.MarkTrue(config, bootstrapv1.ProvisionedCondition)
+	default:
+		conditions.MarkFalse(config, bootstrapv1.ProvisionedCondition,
+			bootstrapv1.WaitingForProvisioningReason, clusterv1.ConditionSeverityInfo,
+			"BMH.provisioning.state: %s", bmh.Status.Provisioning.State)
+	}
+	config.Status.Phase = phaseFromHost(bmh)
+
+	// Revoke the bootstrap token once the node registers or the token expires
+	tokenTTL, err := configMgr.ReconcileBootstrapToken(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The host and the node are followed through their watches from here
+	return ctrl.Result{RequeueAfter: tokenTTL}, nil
+}
+
+// reconcileUnavailableHost applies the unavailable host policy of the
+// NodeConfig to a BareMetalHost that cannot be used, and records the action
+// as an event.
+func (r *NodeConfigReconciler) reconcileUnavailableHost(ctx context.Context,
+	config *bootstrapv1.NodeConfig, host *bmhv1.BareMetalHost) (ctrl.Result, error) {
+	log := ctrllog.FromContext(ctx)
+	msg := fmt.Sprintf("The found BareMetalHost is not available. BMH.provisioning.state: %s, BMH.operationalStatus: %s",
+		host.Status.Provisioning.State, host.Status.OperationalStatus)
+	log.Info(msg, "policy", config.UnavailableHostPolicy())
+
+	switch config.UnavailableHostPolicy() {
+	case bootstrapv1.UnavailableHostWait:
+		// Record the Event once, not on every requeue
+		if cond := conditions.Get(config, bootstrapv1.BareMetalHostCreatedCondition); cond == nil ||
+			cond.Reason != bootstrapv1.BareMetalHostUnavailableReason || cond.Message != msg {
+			r.Recorder.Eventf(config, corev1.EventTypeNormal, "WaitingForHost", "%s. Waiting for it to recover", msg)
+		}
+		conditions.MarkFalse(config, bootstrapv1.BareMetalHostCreatedCondition,
+			bootstrapv1.BareMetalHostUnavailableReason, clusterv1.ConditionSeverityWarning, "%s", msg)
+		config.Status.Phase = bootstrapv1.NodeConfigPhasePending
+		// Requeue with the backoff of the controller until the host recovers
+		return ctrl.Result{Requeue: true}, nil
+
+	case bootstrapv1.UnavailableHostRecreate:
+		conditions.MarkFalse(config, bootstrapv1.BareMetalHostCreatedCondition,
+			bootstrapv1.BareMetalHostUnavailableReason, clusterv1.ConditionSeverityWarning, "%s", msg)
+		config.Status.Phase = bootstrapv1.NodeConfigPhaseRegistering
+		if host.DeletionTimestamp.IsZero() {
+			r.Recorder.Eventf(config, corev1.EventTypeNormal, "RecreatingHost", "%s. Deleting it to register it again", msg)
+			if err := r.Client.Delete(ctx, host); err != nil && !apierrors.IsNotFound(err) {
+				return ctrl.Result{}, errors.Wrapf(err, "Failed to delete the BMH %s/%s", host.Namespace, host.Name)
+			}
+		}
+		// The host watch brings us back once it is gone to create it again
+		return ctrl.Result{}, nil
+
+	case bootstrapv1.UnavailableHostDelete:
+		conditions.MarkFalse(config, bootstrapv1.BareMetalHostCreatedCondition,
+			bootstrapv1.BareMetalHostUnavailableReason, clusterv1.ConditionSeverityError, "%s", msg)
+		config.Status.Phase = bootstrapv1.NodeConfigPhaseFailed
+		r.Recorder.Eventf(config, corev1.EventTypeWarning, "DeletingHost", "%s. Deleting the BareMetalHost and the NodeConfig", msg)
+		if err := r.Client.Delete(ctx, host); err != nil && !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, errors.Wrapf(err, "Failed to delete the BMH %s/%s", host.Namespace, host.Name)
+		}
+		if err := r.Client.Delete(ctx, config); err != nil && !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, errors.Wrapf(err, "Failed to delete the NodeConfig %s/%s", config.Namespace, config.Name)
+		}
+		return ctrl.Result{}, nil
+
+	default:
+		conditions.MarkFalse(config, bootstrapv1.BareMetalHostCreatedCondition,
+			bootstrapv1.BareMetalHostUnavailableReason, clusterv1.ConditionSeverityError, "%s", msg)
+		config.Status.Phase = bootstrapv1.NodeConfigPhaseFailed
+		r.Recorder.Event(config, corev1.EventTypeWarning, "HostUnavailable", msg)
+		return ctrl.Result{}, nil
+	}
+}
+
+// reconcileDelete releases the BareMetalHost as the deletion policy says and
+// removes the finalizer once it is done.
+func (r *NodeConfigReconciler) reconcileDelete(ctx context.Context, configMgr *util.ConfigManager) (ctrl.Result, error) {
+	config := configMgr.NodeConfig
+	config.Status.Phase = bootstrapv1.NodeConfigPhaseDeprovisioning
+	conditions.MarkFalse(config, bootstrapv1.HostAssociatedCondition,
+		clusterv1.DeletingReason, clusterv1.ConditionSeverityInfo,
+		"Applying the %s deletion policy", config.DeletionPolicy())
+
+	if err := configMgr.RevokeBootstrapToken(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	configMgr.NodeConfig.Status.Ready = true
-	if err := patchHelper.Patch(ctx, config); err != nil {
-		log.Info("Failed to patch the nodeconfig")
+	released, err := configMgr.ReleaseHost(ctx)
+	if err != nil {
+		conditions.MarkFalse(config, bootstrapv1.HostAssociatedCondition,
+			clusterv1.DeletionFailedReason, clusterv1.ConditionSeverityWarning, "%s", err.Error())
+		return ctrl.Result{}, err
+	}
+	if !released {
+		// The host watch brings us back once it changes
+		return ctrl.Result{}, nil
+	}
+
+	// The addresses are free once the OS is gone from the host. A detached
+	// host goes on using them, so they stay allocated.
+	if config.DeletionPolicy() == bootstrapv1.DeletionDetach {
+		if err := configMgr.OrphanIPClaims(ctx); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err := configMgr.ReleaseIPClaims(ctx); err != nil {
 		return ctrl.Result{}, err
 	}
 
+	controllerutil.RemoveFinalizer(config, bootstrapv1.NodeConfigFinalizer)
 	return ctrl.Result{}, nil
 }
+
+// phaseFromHost maps the BareMetalHost of the NodeConfig to the matching
+// NodeConfig phase. A host reporting an error fails the NodeConfig whatever
+// its provisioning state, and a host that is not created yet is registering.
+func phaseFromHost(host *bmhv1.BareMetalHost) bootstrapv1.NodeConfigPhase {
+	if host == nil {
+		return bootstrapv1.NodeConfigPhaseRegistering
+	}
+	if host.Status.OperationalStatus == bmhv1.OperationalStatusError {
+		return bootstrapv1.NodeConfigPhaseFailed
+	}
+	switch host.Status.Provisioning.State {
+	case bmhv1.StateInspecting, bmhv1.StateMatchProfile:
+		return bootstrapv1.NodeConfigPhaseInspecting
+	case bmhv1.StatePreparing, bmhv1.StateReady, bmhv1.StateAvailable, bmhv1.StateProvisioning:
+		return bootstrapv1.NodeConfigPhaseProvisioning
+	case bmhv1.StateProvisioned, bmhv1.StateExternallyProvisioned:
+		return bootstrapv1.NodeConfigPhaseProvisioned
+	case bmhv1.StateDeprovisioning, bmhv1.StateDeleting:
+		return bootstrapv1.NodeConfigPhaseDeprovisioning
+	default:
+		return bootstrapv1.NodeConfigPhaseRegistering
+	}
+}
+
+// patchNodeConfig summarizes the NodeConfig conditions into the Ready
+// condition and patches the object.
+func patchNodeConfig(ctx context.Context, patchHelper *patch.Helper, config *bootstrapv1.NodeConfig, options ...patch.Option) error {
+	conditions.SetSummary(config,
+		conditions.WithConditions(
+			bootstrapv1.UserDataRenderedCondition,
+			bootstrapv1.BareMetalHostCreatedCondition,
+			bootstrapv1.HardwareRequirementsMetCondition,
+			bootstrapv1.HostAssociatedCondition,
+			bootstrapv1.ProvisionedCondition,
+		),
+		conditions.WithStepCounterIf(config.ObjectMeta.DeletionTimestamp.IsZero()),
+	)
+	config.Status.Ready = conditions.IsTrue(config, clusterv1.ReadyCondition)
+
+	options = append(options,
+		patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
+			clusterv1.ReadyCondition,
+			bootstrapv1.UserDataRenderedCondition,
+			bootstrapv1.BareMetalHostCreatedCondition,
+			bootstrapv1.HardwareRequirementsMetCondition,
+			bootstrapv1.HostAssociatedCondition,
+			bootstrapv1.ProvisionedCondition,
+		}},
+	)
+	return patchHelper.Patch(ctx, config, options...)
+}
@@ -0,0 +1,808 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	bmhv1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	"github.com/tmax-cloud/nodeconfig-operator/util"
+)
+
+func newTestScheme(g *WithT) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(bmhv1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+	return scheme
+}
+
+// newTestConfigManager returns a config manager for the NodeConfig.
+func newTestConfigManager(g *WithT, cl client.Client, config *bootstrapv1.NodeConfig) *util.ConfigManager {
+	configMgr, err := (&util.ConfigManager{}).NewConfigManager(cl, config, log.Log)
+	g.Expect(err).NotTo(HaveOccurred())
+	return configMgr
+}
+
+func TestReconcileDeleteIPClaims(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = []struct {
+		name       string
+		policy     bootstrapv1.DeletionPolicy
+		wantClaims int
+		wantAlloc  map[string]string
+	}{
+		{
+			name:       "detach keeps the address",
+			policy:     bootstrapv1.DeletionDetach,
+			wantClaims: 1,
+			wantAlloc:  map[string]string{"node-eno1": "192.168.100.3"},
+		},
+		{
+			name:   "deprovision releases the address",
+			policy: bootstrapv1.DeletionDeprovision,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config := &bootstrapv1.NodeConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "node", Namespace: "default", UID: "node-uid",
+					Finalizers: []string{bootstrapv1.NodeConfigFinalizer},
+				},
+				Spec: bootstrapv1.NodeConfigSpec{DeletionPolicy: tt.policy},
+			}
+			pool := &bootstrapv1.IPPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool", Namespace: "default"},
+				Spec:       bootstrapv1.IPPoolSpec{CIDR: "192.168.100.0/24"},
+				Status:     bootstrapv1.IPPoolStatus{Allocations: map[string]string{"node-eno1": "192.168.100.3"}},
+			}
+			claim := &bootstrapv1.IPClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "node-eno1", Namespace: "default",
+					Labels: map[string]string{bootstrapv1.IPClaimNodeConfigLabel: "node"},
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: bootstrapv1.GroupVersion.String(), Kind: "NodeConfig",
+						Name: "node", UID: "node-uid", Controller: pointer.BoolPtr(true),
+					}},
+				},
+				Spec:   bootstrapv1.IPClaimSpec{PoolRef: corev1.LocalObjectReference{Name: "pool"}},
+				Status: bootstrapv1.IPClaimStatus{Address: "192.168.100.3/24"},
+			}
+			cl := fake.NewClientBuilder().WithScheme(newTestScheme(g)).WithObjects(config, pool, claim).Build()
+			r := &NodeConfigReconciler{Client: cl, Recorder: record.NewFakeRecorder(32)}
+
+			_, err := r.reconcileDelete(ctx, newTestConfigManager(g, cl, config))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(controllerutil.ContainsFinalizer(config, bootstrapv1.NodeConfigFinalizer)).To(BeFalse())
+
+			claims := &bootstrapv1.IPClaimList{}
+			g.Expect(cl.List(ctx, claims)).To(Succeed())
+			g.Expect(claims.Items).To(HaveLen(tt.wantClaims))
+			for _, c := range claims.Items {
+				g.Expect(c.OwnerReferences).To(BeEmpty())
+				g.Expect(c.Labels).NotTo(HaveKey(bootstrapv1.IPClaimNodeConfigLabel))
+			}
+			released := &bootstrapv1.IPPool{}
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(pool), released)).To(Succeed())
+			if tt.wantAlloc == nil {
+				g.Expect(released.Status.Allocations).To(BeEmpty())
+			} else {
+				g.Expect(released.Status.Allocations).To(Equal(tt.wantAlloc))
+			}
+
+			// A NodeConfig created again under the name does not take the kept address
+			if tt.policy == bootstrapv1.DeletionDetach {
+				again := &bootstrapv1.NodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default", UID: "new-uid"}}
+				network := &bootstrapv1.Network{Ethernets: []bootstrapv1.Ethernet{{
+					Name:            "eno1",
+					InterfaceConfig: bootstrapv1.InterfaceConfig{IPPoolRef: &corev1.LocalObjectReference{Name: "pool"}},
+				}}}
+				again.Spec.Network = network
+				_, err := newTestConfigManager(g, cl, again).CreateNetworkData(ctx)
+				g.Expect(err).To(MatchError(ContainSubstring("detached host")))
+			}
+		})
+	}
+}
+
+func TestReconcileUnavailableHost(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = []struct {
+		name       string
+		policy     bootstrapv1.UnavailableHostPolicy
+		wantResult ctrl.Result
+		wantPhase  bootstrapv1.NodeConfigPhase
+		wantEvent  string
+		wantHost   bool
+		wantConfig bool
+	}{
+		{
+			name:       "fail keeps both",
+			wantPhase:  bootstrapv1.NodeConfigPhaseFailed,
+			wantEvent:  "HostUnavailable",
+			wantHost:   true,
+			wantConfig: true,
+		},
+		{
+			name:       "wait requeues",
+			policy:     bootstrapv1.UnavailableHostWait,
+			wantResult: ctrl.Result{Requeue: true},
+			wantPhase:  bootstrapv1.NodeConfigPhasePending,
+			wantEvent:  "WaitingForHost",
+			wantHost:   true,
+			wantConfig: true,
+		},
+		{
+			name:       "recreate deletes the host",
+			policy:     bootstrapv1.UnavailableHostRecreate,
+			wantPhase:  bootstrapv1.NodeConfigPhaseRegistering,
+			wantEvent:  "RecreatingHost",
+			wantConfig: true,
+		},
+		{
+			name:      "delete deletes both",
+			policy:    bootstrapv1.UnavailableHostDelete,
+			wantPhase: bootstrapv1.NodeConfigPhaseFailed,
+			wantEvent: "DeletingHost",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config := &bootstrapv1.NodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default"},
+				Spec:       bootstrapv1.NodeConfigSpec{UnavailableHostPolicy: tt.policy},
+			}
+			host := &bmhv1.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default"},
+				Status: bmhv1.BareMetalHostStatus{
+					OperationalStatus: bmhv1.OperationalStatusError,
+					Provisioning:      bmhv1.ProvisionStatus{State: bmhv1.StateRegistering},
+				},
+			}
+			cl := fake.NewClientBuilder().WithScheme(newTestScheme(g)).WithObjects(config, host).Build()
+			recorder := record.NewFakeRecorder(32)
+			r := &NodeConfigReconciler{Client: cl, Recorder: recorder}
+
+			result, err := r.reconcileUnavailableHost(ctx, config, host)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(result).To(Equal(tt.wantResult))
+			g.Expect(config.Status.Phase).To(Equal(tt.wantPhase))
+			g.Expect(conditions.GetReason(config, bootstrapv1.BareMetalHostCreatedCondition)).
+				To(Equal(bootstrapv1.BareMetalHostUnavailableReason))
+			g.Expect(recorder.Events).To(Receive(ContainSubstring(tt.wantEvent)))
+
+			err = cl.Get(ctx, client.ObjectKeyFromObject(host), &bmhv1.BareMetalHost{})
+			g.Expect(err == nil).To(Equal(tt.wantHost))
+			g.Expect(err == nil || apierrors.IsNotFound(err)).To(BeTrue())
+			err = cl.Get(ctx, client.ObjectKeyFromObject(config), &bootstrapv1.NodeConfig{})
+			g.Expect(err == nil).To(Equal(tt.wantConfig))
+			g.Expect(err == nil || apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	}
+}
+
+func TestReconcileUnavailableHostWaitEvent(t *testing.T) {
+	ctx := context.Background()
+	g := NewWithT(t)
+
+	config := &bootstrapv1.NodeConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default"},
+		Spec:       bootstrapv1.NodeConfigSpec{UnavailableHostPolicy: bootstrapv1.UnavailableHostWait},
+	}
+	host := &bmhv1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default"},
+		Status: bmhv1.BareMetalHostStatus{
+			OperationalStatus: bmhv1.OperationalStatusError,
+			Provisioning:      bmhv1.ProvisionStatus{State: bmhv1.StateRegistering},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(newTestScheme(g)).WithObjects(config, host).Build()
+	recorder := record.NewFakeRecorder(32)
+	r := &NodeConfigReconciler{Client: cl, Recorder: recorder}
+
+	// The Event is recorded once while the host stays the same
+	for i := 0; i < 3; i++ {
+		_, err := r.reconcileUnavailableHost(ctx, config, host)
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	g.Expect(recorder.Events).To(HaveLen(1))
+
+	// and again when it changes
+	host.Status.Provisioning.State = bmhv1.StateInspecting
+	_, err := r.reconcileUnavailableHost(ctx, config, host)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(recorder.Events).To(HaveLen(2))
+}
+
+func TestReconcileDeletePolicies(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = []struct {
+		name          string
+		policy        bootstrapv1.DeletionPolicy
+		wantFinalizer bool
+		wantHost      bool
+	}{
+		{
+			name:     "detach",
+			policy:   bootstrapv1.DeletionDetach,
+			wantHost: true,
+		},
+		{
+			name:          "deprovision",
+			policy:        bootstrapv1.DeletionDeprovision,
+			wantFinalizer: true,
+			wantHost:      true,
+		},
+		{
+			name:          "delete",
+			policy:        bootstrapv1.DeletionDelete,
+			wantFinalizer: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config := &bootstrapv1.NodeConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "node", Namespace: "default", UID: "node-uid",
+					Finalizers: []string{bootstrapv1.NodeConfigFinalizer},
+				},
+				Spec: bootstrapv1.NodeConfigSpec{DeletionPolicy: tt.policy},
+			}
+			host := &bmhv1.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default"},
+				Spec: bmhv1.BareMetalHostSpec{
+					Image:    &bmhv1.Image{URL: "http://192.168.111.1:6180/images/node.qcow2"},
+					UserData: &corev1.SecretReference{Name: "node", Namespace: "default"},
+				},
+				Status: bmhv1.BareMetalHostStatus{
+					OperationalStatus: bmhv1.OperationalStatusOK,
+					Provisioning:      bmhv1.ProvisionStatus{State: bmhv1.StateProvisioned},
+				},
+			}
+			cl := fake.NewClientBuilder().WithScheme(newTestScheme(g)).WithObjects(config, host).Build()
+			r := &NodeConfigReconciler{Client: cl, Recorder: record.NewFakeRecorder(32)}
+
+			_, err := r.reconcileDelete(ctx, newTestConfigManager(g, cl, config))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(config.Status.Phase).To(Equal(bootstrapv1.NodeConfigPhaseDeprovisioning))
+			g.Expect(controllerutil.ContainsFinalizer(config, bootstrapv1.NodeConfigFinalizer)).To(Equal(tt.wantFinalizer))
+
+			got := &bmhv1.BareMetalHost{}
+			err = cl.Get(ctx, client.ObjectKeyFromObject(host), got)
+			if !tt.wantHost {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(got.Spec.Image == nil).To(Equal(tt.policy == bootstrapv1.DeletionDeprovision))
+			}
+			if !tt.wantFinalizer {
+				return
+			}
+
+			// The finalizer goes once the host is deprovisioned or gone
+			if tt.wantHost {
+				got.Status.Provisioning.State = bmhv1.StateAvailable
+				g.Expect(cl.Update(ctx, got)).To(Succeed())
+			}
+			_, err = r.reconcileDelete(ctx, newTestConfigManager(g, cl, config))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(controllerutil.ContainsFinalizer(config, bootstrapv1.NodeConfigFinalizer)).To(BeFalse())
+		})
+	}
+}
+
+func TestReconcileWithoutImage(t *testing.T) {
+	ctx := context.Background()
+	g := NewWithT(t)
+
+	config := &bootstrapv1.NodeConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node", Namespace: "default", UID: "node-uid",
+			Finalizers: []string{bootstrapv1.NodeConfigFinalizer},
+		},
+		Spec: bootstrapv1.NodeConfigSpec{
+			BMC: &bootstrapv1.BMC{
+				Address:              "192.168.111.204",
+				CredentialsSecretRef: &corev1.LocalObjectReference{Name: "node-bmc-secret"},
+			},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(newTestScheme(g)).WithObjects(config).Build()
+	r := &NodeConfigReconciler{Client: cl, Recorder: record.NewFakeRecorder(32)}
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(config)})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	got := &bootstrapv1.NodeConfig{}
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(config), got)).To(Succeed())
+	g.Expect(got.Status.Phase).To(Equal(bootstrapv1.NodeConfigPhasePending))
+	g.Expect(conditions.IsFalse(got, bootstrapv1.HostAssociatedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(got, bootstrapv1.HostAssociatedCondition)).To(Equal(bootstrapv1.ImageNotSetReason))
+
+	// No host is registered without an image
+	hosts := &bmhv1.BareMetalHostList{}
+	g.Expect(cl.List(ctx, hosts)).To(Succeed())
+	g.Expect(hosts.Items).To(BeEmpty())
+}
+
+// newMapTestConfig returns a NodeConfig in the namespace, changed by the
+// mutate functions.
+func newMapTestConfig(namespace, name string, mutate ...func(*bootstrapv1.NodeConfig)) *bootstrapv1.NodeConfig {
+	config := &bootstrapv1.NodeConfig{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	for _, m := range mutate {
+		m(config)
+	}
+	return config
+}
+
+// requestsFor returns the reconcile requests of the NodeConfigs.
+func requestsFor(configs ...*bootstrapv1.NodeConfig) []reconcile.Request {
+	var result []reconcile.Request
+	for _, config := range configs {
+		result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(config)})
+	}
+	return result
+}
+
+func TestBareMetalHostToNodeConfig(t *testing.T) {
+	claimable := func(host *bmhv1.BareMetalHost) *bmhv1.BareMetalHost {
+		host.Labels = map[string]string{"rack": "r1"}
+		host.Status.Provisioning.State = bmhv1.StateReady
+		host.Status.OperationalStatus = bmhv1.OperationalStatusOK
+		return host
+	}
+	selecting := newMapTestConfig("default", "selecting", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.HostSelector = &bootstrapv1.HostSelector{MatchLabels: map[string]string{"rack": "r1"}}
+	})
+	otherRack := newMapTestConfig("default", "other-rack", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.HostSelector = &bootstrapv1.HostSelector{MatchLabels: map[string]string{"rack": "r2"}}
+	})
+	withHost := newMapTestConfig("default", "with-host", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.HostSelector = &bootstrapv1.HostSelector{MatchLabels: map[string]string{"rack": "r1"}}
+		c.Status.HostRef = &corev1.ObjectReference{Name: "other-host", Namespace: "default"}
+	})
+	otherNamespace := newMapTestConfig("other", "selecting", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.HostSelector = &bootstrapv1.HostSelector{MatchLabels: map[string]string{"rack": "r1"}}
+	})
+	configs := []client.Object{selecting, otherRack, withHost, otherNamespace}
+
+	var tests = []struct {
+		name   string
+		object client.Object
+		want   []reconcile.Request
+	}{
+		{
+			name: "consumed host maps to its consumer only",
+			object: claimable(&bmhv1.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: "host", Namespace: "default"},
+				Spec: bmhv1.BareMetalHostSpec{ConsumerRef: &corev1.ObjectReference{
+					Kind: "NodeConfig", Namespace: "default", Name: "consumer",
+				}},
+			}),
+			want: requestsFor(newMapTestConfig("default", "consumer")),
+		},
+		{
+			name: "host consumed by another kind maps to its namesake",
+			object: &bmhv1.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: "host", Namespace: "default"},
+				Spec: bmhv1.BareMetalHostSpec{ConsumerRef: &corev1.ObjectReference{
+					Kind: "Metal3Machine", Namespace: "default", Name: "machine",
+				}},
+			},
+			want: requestsFor(newMapTestConfig("default", "host")),
+		},
+		{
+			name:   "claimable host maps to the selecting NodeConfigs of its namespace",
+			object: claimable(&bmhv1.BareMetalHost{ObjectMeta: metav1.ObjectMeta{Name: "host", Namespace: "default"}}),
+			want:   requestsFor(newMapTestConfig("default", "host"), selecting),
+		},
+		{
+			name: "host that cannot be claimed maps to its namesake",
+			object: &bmhv1.BareMetalHost{ObjectMeta: metav1.ObjectMeta{
+				Name: "host", Namespace: "default", Labels: map[string]string{"rack": "r1"},
+			}},
+			want: requestsFor(newMapTestConfig("default", "host")),
+		},
+		{
+			name:   "claimable host in a namespace without NodeConfigs",
+			object: claimable(&bmhv1.BareMetalHost{ObjectMeta: metav1.ObjectMeta{Name: "host", Namespace: "empty"}}),
+			want:   requestsFor(newMapTestConfig("empty", "host")),
+		},
+		{
+			name:   "wrong object type",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "host", Namespace: "default"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			cl := fake.NewClientBuilder().WithScheme(newTestScheme(g)).WithObjects(configs...).Build()
+			r := &NodeConfigReconciler{Client: cl}
+
+			got := r.BareMetalHostToNodeConfig(tt.object)
+			if tt.want == nil {
+				g.Expect(got).To(BeEmpty())
+				return
+			}
+			g.Expect(got).To(ConsistOf(tt.want))
+		})
+	}
+}
+
+func TestSecretToNodeConfigs(t *testing.T) {
+	secretFile := func(name string) bootstrapv1.File {
+		return bootstrapv1.File{Path: "/etc/" + name, ContentFrom: &bootstrapv1.FileSource{
+			Secret: &bootstrapv1.FileSourceKey{Name: name, Key: "content"},
+		}}
+	}
+	bmc := newMapTestConfig("default", "bmc", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.BMC = &bootstrapv1.BMC{CredentialsSecretRef: &corev1.LocalObjectReference{Name: "bmc-secret"}}
+	})
+	certificateKey := newMapTestConfig("default", "certificate-key", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.Kubeadm = &bootstrapv1.KubeadmSpec{CertificateKeySecretRef: &corev1.LocalObjectReference{Name: "certificate-key"}}
+	})
+	file := newMapTestConfig("default", "file", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.Files = []bootstrapv1.File{secretFile("file-secret")}
+	})
+	referred := newMapTestConfig("default", "referred", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.ProfileRef = &corev1.LocalObjectReference{Name: "profile"}
+	})
+	applied := newMapTestConfig("default", "applied", func(c *bootstrapv1.NodeConfig) {
+		c.Status.AppliedProfile = &bootstrapv1.AppliedProfile{Name: "profile", Generation: 1}
+	})
+	otherNamespace := newMapTestConfig("other", "file", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.Files = []bootstrapv1.File{secretFile("file-secret")}
+		c.Spec.ProfileRef = &corev1.LocalObjectReference{Name: "profile"}
+	})
+	profile := &bootstrapv1.NodeConfigProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "profile"},
+		Spec:       bootstrapv1.NodeConfigProfileSpec{Files: []bootstrapv1.File{secretFile("profile-secret")}},
+	}
+	objects := []client.Object{bmc, certificateKey, file, referred, applied, otherNamespace, profile}
+
+	var tests = []struct {
+		name   string
+		object client.Object
+		want   []reconcile.Request
+	}{
+		{
+			name:   "BMC credentials",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "bmc-secret", Namespace: "default"}},
+			want:   requestsFor(bmc),
+		},
+		{
+			name:   "certificate key",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "certificate-key", Namespace: "default"}},
+			want:   requestsFor(certificateKey),
+		},
+		{
+			name:   "file content",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "file-secret", Namespace: "default"}},
+			want:   requestsFor(file),
+		},
+		{
+			name:   "file content of the profile referred to or applied",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "profile-secret", Namespace: "default"}},
+			want:   requestsFor(referred, applied),
+		},
+		{
+			name:   "other namespace",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "file-secret", Namespace: "other"}},
+			want:   requestsFor(otherNamespace),
+		},
+		{
+			name:   "unused Secret",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unused", Namespace: "default"}},
+		},
+		{
+			name:   "wrong object type",
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "bmc-secret", Namespace: "default"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			cl := fake.NewClientBuilder().WithScheme(newTestScheme(g)).WithObjects(objects...).Build()
+			r := &NodeConfigReconciler{Client: cl}
+
+			got := r.SecretToNodeConfigs(tt.object)
+			if tt.want == nil {
+				g.Expect(got).To(BeEmpty())
+				return
+			}
+			g.Expect(got).To(ConsistOf(tt.want))
+		})
+	}
+}
+
+func TestConfigMapToNodeConfigs(t *testing.T) {
+	configMapFile := func(name string) bootstrapv1.File {
+		return bootstrapv1.File{Path: "/etc/" + name, ContentFrom: &bootstrapv1.FileSource{
+			ConfigMap: &bootstrapv1.FileSourceKey{Name: name, Key: "content"},
+		}}
+	}
+	keyRef := func(name string) *corev1.ConfigMapKeySelector {
+		return &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "key"}
+	}
+	file := newMapTestConfig("default", "file", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.Files = []bootstrapv1.File{configMapFile("file-cm")}
+	})
+	yum := newMapTestConfig("default", "yum", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.Packages = &bootstrapv1.Packages{YumRepos: []bootstrapv1.YumRepo{{ID: "kubernetes", GPGKeyRef: keyRef("keys")}}}
+	})
+	apt := newMapTestConfig("default", "apt", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.Packages = &bootstrapv1.Packages{AptSources: []bootstrapv1.AptSource{{Name: "kubernetes", KeyRef: keyRef("keys")}}}
+	})
+	applied := newMapTestConfig("default", "applied", func(c *bootstrapv1.NodeConfig) {
+		c.Status.AppliedProfile = &bootstrapv1.AppliedProfile{Name: "profile", Generation: 1}
+	})
+	otherNamespace := newMapTestConfig("other", "file", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.Files = []bootstrapv1.File{configMapFile("file-cm")}
+	})
+	profile := &bootstrapv1.NodeConfigProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "profile"},
+		Spec:       bootstrapv1.NodeConfigProfileSpec{Files: []bootstrapv1.File{configMapFile("profile-cm")}},
+	}
+	objects := []client.Object{file, yum, apt, applied, otherNamespace, profile}
+
+	var tests = []struct {
+		name   string
+		object client.Object
+		want   []reconcile.Request
+	}{
+		{
+			name:   "file content",
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "file-cm", Namespace: "default"}},
+			want:   requestsFor(file),
+		},
+		{
+			name:   "GPG keys of yum repositories and apt sources",
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "keys", Namespace: "default"}},
+			want:   requestsFor(yum, apt),
+		},
+		{
+			name:   "file content of the applied profile",
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "profile-cm", Namespace: "default"}},
+			want:   requestsFor(applied),
+		},
+		{
+			name:   "other namespace",
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "file-cm", Namespace: "other"}},
+			want:   requestsFor(otherNamespace),
+		},
+		{
+			name:   "unused ConfigMap",
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unused", Namespace: "default"}},
+		},
+		{
+			name:   "wrong object type",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "file-cm", Namespace: "default"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			cl := fake.NewClientBuilder().WithScheme(newTestScheme(g)).WithObjects(objects...).Build()
+			r := &NodeConfigReconciler{Client: cl}
+
+			got := r.ConfigMapToNodeConfigs(tt.object)
+			if tt.want == nil {
+				g.Expect(got).To(BeEmpty())
+				return
+			}
+			g.Expect(got).To(ConsistOf(tt.want))
+		})
+	}
+}
+
+func TestNodeConfigProfileToNodeConfigs(t *testing.T) {
+	referred := newMapTestConfig("default", "referred", func(c *bootstrapv1.NodeConfig) {
+		c.Spec.ProfileRef = &corev1.LocalObjectReference{Name: "profile"}
+	})
+	referredElsewhere := newMapTestConfig("default", "referred-elsewhere", func(c *bootstrapv1.NodeConfig) {
+		c.Labels = map[string]string{"role": "worker"}
+		c.Spec.ProfileRef = &corev1.LocalObjectReference{Name: "other"}
+	})
+	selected := newMapTestConfig("default", "selected", func(c *bootstrapv1.NodeConfig) {
+		c.Labels = map[string]string{"role": "worker"}
+	})
+	selectedElsewhere := newMapTestConfig("other", "selected", func(c *bootstrapv1.NodeConfig) {
+		c.Labels = map[string]string{"role": "worker"}
+	})
+	applied := newMapTestConfig("default", "applied", func(c *bootstrapv1.NodeConfig) {
+		c.Status.AppliedProfile = &bootstrapv1.AppliedProfile{Name: "profile", Generation: 1}
+	})
+	unrelated := newMapTestConfig("default", "unrelated", func(c *bootstrapv1.NodeConfig) {
+		c.Labels = map[string]string{"role": "master"}
+	})
+	configs := []client.Object{referred, referredElsewhere, selected, selectedElsewhere, applied, unrelated}
+
+	var tests = []struct {
+		name   string
+		object client.Object
+		want   []reconcile.Request
+	}{
+		{
+			name: "selector",
+			object: &bootstrapv1.NodeConfigProfile{
+				ObjectMeta: metav1.ObjectMeta{Name: "profile"},
+				Spec: bootstrapv1.NodeConfigProfileSpec{Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"role": "worker"},
+				}},
+			},
+			want: requestsFor(referred, selected, selectedElsewhere, applied),
+		},
+		{
+			name:   "without a selector",
+			object: &bootstrapv1.NodeConfigProfile{ObjectMeta: metav1.ObjectMeta{Name: "profile"}},
+			want:   requestsFor(referred, applied),
+		},
+		{
+			name: "unused profile",
+			object: &bootstrapv1.NodeConfigProfile{
+				ObjectMeta: metav1.ObjectMeta{Name: "unused"},
+				Spec: bootstrapv1.NodeConfigProfileSpec{Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"role": "storage"},
+				}},
+			},
+		},
+		{
+			name:   "wrong object type",
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "profile", Namespace: "default"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			cl := fake.NewClientBuilder().WithScheme(newTestScheme(g)).WithObjects(configs...).Build()
+			r := &NodeConfigReconciler{Client: cl}
+
+			got := r.NodeConfigProfileToNodeConfigs(tt.object)
+			if tt.want == nil {
+				g.Expect(got).To(BeEmpty())
+				return
+			}
+			g.Expect(got).To(ConsistOf(tt.want))
+		})
+	}
+}
+
+func TestNodeToNodeConfigs(t *testing.T) {
+	waiting := newMapTestConfig("default", "node", func(c *bootstrapv1.NodeConfig) {
+		c.Status.BootstrapTokenID = "abcdef"
+	})
+	waitingElsewhere := newMapTestConfig("other", "node", func(c *bootstrapv1.NodeConfig) {
+		c.Status.BootstrapTokenID = "ghijkl"
+	})
+	hostNamed := newMapTestConfig("default", "host-named", func(c *bootstrapv1.NodeConfig) {
+		c.Status.HostRef = &corev1.ObjectReference{Name: "host", Namespace: "default"}
+		c.Status.BootstrapTokenID = "mnopqr"
+	})
+	revoked := newMapTestConfig("default", "revoked", func(c *bootstrapv1.NodeConfig) {
+		c.Status.HostRef = &corev1.ObjectReference{Name: "host", Namespace: "default"}
+	})
+	configs := []client.Object{waiting, waitingElsewhere, hostNamed, revoked}
+
+	var tests = []struct {
+		name   string
+		object client.Object
+		want   []reconcile.Request
+	}{
+		{
+			name:   "NodeConfigs of every namespace waiting for the node",
+			object: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}},
+			want:   requestsFor(waiting, waitingElsewhere),
+		},
+		{
+			name:   "node named after the host",
+			object: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "host"}},
+			want:   requestsFor(hostNamed),
+		},
+		{
+			name:   "unknown node",
+			object: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "unknown"}},
+		},
+		{
+			name:   "wrong object type",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			cl := fake.NewClientBuilder().WithScheme(newTestScheme(g)).WithObjects(configs...).Build()
+			r := &NodeConfigReconciler{Client: cl}
+
+			got := r.NodeToNodeConfigs(tt.object)
+			if tt.want == nil {
+				g.Expect(got).To(BeEmpty())
+				return
+			}
+			g.Expect(got).To(ConsistOf(tt.want))
+		})
+	}
+}
+
+func TestPhaseFromHost(t *testing.T) {
+	host := func(state bmhv1.ProvisioningState, status bmhv1.OperationalStatus) *bmhv1.BareMetalHost {
+		host := &bmhv1.BareMetalHost{}
+		host.Status.Provisioning.State = state
+		host.Status.OperationalStatus = status
+		return host
+	}
+
+	var tests = []struct {
+		name string
+		host *bmhv1.BareMetalHost
+		want bootstrapv1.NodeConfigPhase
+	}{
+		{"not created yet", nil, bootstrapv1.NodeConfigPhaseRegistering},
+		{"no state yet", host(bmhv1.StateNone, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseRegistering},
+		{"unmanaged", host(bmhv1.StateUnmanaged, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseRegistering},
+		{"registering", host(bmhv1.StateRegistering, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseRegistering},
+		{"inspecting", host(bmhv1.StateInspecting, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseInspecting},
+		{"match profile", host(bmhv1.StateMatchProfile, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseInspecting},
+		{"preparing", host(bmhv1.StatePreparing, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseProvisioning},
+		{"ready", host(bmhv1.StateReady, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseProvisioning},
+		{"available", host(bmhv1.StateAvailable, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseProvisioning},
+		{"provisioning", host(bmhv1.StateProvisioning, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseProvisioning},
+		{"provisioned", host(bmhv1.StateProvisioned, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseProvisioned},
+		{"externally provisioned", host(bmhv1.StateExternallyProvisioned, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseProvisioned},
+		{"deprovisioning", host(bmhv1.StateDeprovisioning, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseDeprovisioning},
+		{"deleting", host(bmhv1.StateDeleting, bmhv1.OperationalStatusOK), bootstrapv1.NodeConfigPhaseDeprovisioning},
+		{"discovered host", host(bmhv1.StateRegistering, bmhv1.OperationalStatusDiscovered), bootstrapv1.NodeConfigPhaseRegistering},
+		{"registration error", host(bmhv1.StateRegistering, bmhv1.OperationalStatusError), bootstrapv1.NodeConfigPhaseFailed},
+		{"inspection error", host(bmhv1.StateInspecting, bmhv1.OperationalStatusError), bootstrapv1.NodeConfigPhaseFailed},
+		{"provisioning error", host(bmhv1.StateProvisioning, bmhv1.OperationalStatusError), bootstrapv1.NodeConfigPhaseFailed},
+		{"power management error", host(bmhv1.StateProvisioned, bmhv1.OperationalStatusError), bootstrapv1.NodeConfigPhaseFailed},
+		{"deprovisioning error", host(bmhv1.StateDeprovisioning, bmhv1.OperationalStatusError), bootstrapv1.NodeConfigPhaseFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(phaseFromHost(tt.host)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/tmax-cloud/nodeconfig-operator/util"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+)
+
+// NodeConfigSetReconciler reconciles a NodeConfigSet object
+type NodeConfigSetReconciler struct {
+	Client   client.Client
+	Recorder record.EventRecorder
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeConfigSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&bootstrapv1.NodeConfigSet{}).
+		Owns(&bootstrapv1.NodeConfig{}).
+		Watches(
+			&source.Kind{Type: &bootstrapv1.NodeConfigTemplate{}},
+			handler.EnqueueRequestsFromMapFunc(r.NodeConfigTemplateToNodeConfigSets),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.ConfigMapToNodeConfigSets),
+		).
+		Complete(r)
+}
+
+// NodeConfigTemplateToNodeConfigSets maps a NodeConfigTemplate event to the
+// NodeConfigSets created from it.
+func (r *NodeConfigSetReconciler) NodeConfigTemplateToNodeConfigSets(o client.Object) []reconcile.Request {
+	tmpl, ok := o.(*bootstrapv1.NodeConfigTemplate)
+	if !ok {
+		return nil
+	}
+
+	setList := &bootstrapv1.NodeConfigSetList{}
+	if err := r.Client.List(context.TODO(), setList, client.InNamespace(tmpl.Namespace)); err != nil {
+		return nil
+	}
+
+	var result []reconcile.Request
+	for i := range setList.Items {
+		set := &setList.Items[i]
+		if set.Spec.TemplateRef.Name != tmpl.Name {
+			continue
+		}
+		result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(set)})
+	}
+	return result
+}
+
+// ConfigMapToNodeConfigSets maps a ConfigMap event to the NodeConfigSets
+// reading their inventory from it.
+func (r *NodeConfigSetReconciler) ConfigMapToNodeConfigSets(o client.Object) []reconcile.Request {
+	configMap, ok := o.(*corev1.ConfigMap)
+	if !ok {
+		return nil
+	}
+
+	setList := &bootstrapv1.NodeConfigSetList{}
+	if err := r.Client.List(context.TODO(), setList, client.InNamespace(configMap.Namespace)); err != nil {
+		return nil
+	}
+
+	var result []reconcile.Request
+	for i := range setList.Items {
+		set := &setList.Items[i]
+		if set.Spec.InventoryRef == nil || set.Spec.InventoryRef.Name != configMap.Name {
+			continue
+		}
+		result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(set)})
+	}
+	return result
+}
+
+//+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=nodeconfigsets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=nodeconfigsets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=nodeconfigsets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=bootstrap.tmax.io,resources=nodeconfigtemplates,verbs=get;list;watch
+
+// Reconcile creates a NodeConfig from the template of the NodeConfigSet for
+// every host of the set, deletes those of the hosts it no longer lists, and
+// counts the NodeConfigs that are ready and provisioned.
+func (r *NodeConfigSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, rerr error) {
+	log := ctrllog.FromContext(ctx)
+
+	set := &bootstrapv1.NodeConfigSet{}
+	if err := r.Client.Get(ctx, req.NamespacedName, set); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	// The NodeConfigs are deleted along with the set by their owner reference
+	if !set.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(set, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to init patch helper")
+	}
+	defer func() {
+		var patchOpts []patch.Option
+		if rerr == nil {
+			patchOpts = append(patchOpts, patch.WithStatusObservedGeneration{})
+		}
+		patchOpts = append(patchOpts, patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
+			bootstrapv1.NodeConfigsCreatedCondition,
+		}})
+		if err := patchHelper.Patch(ctx, set, patchOpts...); err != nil {
+			log.Info("failed to Patch nodeconfigset")
+			if rerr == nil {
+				rerr = err
+			}
+		}
+	}()
+
+	configs, err := r.reconcileNodeConfigs(ctx, set)
+	if err != nil {
+		conditions.MarkFalse(set, bootstrapv1.NodeConfigsCreatedCondition,
+			bootstrapv1.NodeConfigsCreateFailedReason, clusterv1.ConditionSeverityError, "%s", err.Error())
+	} else {
+		conditions.MarkTrue(set, bootstrapv1.NodeConfigsCreatedCondition)
+	}
+
+	set.Status.Replicas = int32(len(configs))
+	set.Status.ReadyReplicas = 0
+	set.Status.ProvisionedReplicas = 0
+	for _, config := range configs {
+		if config.Status.Ready {
+			set.Status.ReadyReplicas++
+		}
+		if config.Status.Phase == bootstrapv1.NodeConfigPhaseProvisioned {
+			set.Status.ProvisionedReplicas++
+		}
+	}
+	return ctrl.Result{}, err
+}
+
+// reconcileNodeConfigs creates the NodeConfigs of the hosts of the set and
+// deletes those of the hosts it no longer lists. The NodeConfigs are matched
+// with the hosts by their BMC address, and a new host gets the lowest index
+// not used by the other NodeConfigs of the set. The NodeConfigs of the set
+// are returned, even on error.
+func (r *NodeConfigSetReconciler) reconcileNodeConfigs(ctx context.Context, set *bootstrapv1.NodeConfigSet) ([]*bootstrapv1.NodeConfig, error) {
+	configList := &bootstrapv1.NodeConfigList{}
+	if err := r.Client.List(ctx, configList, client.InNamespace(set.Namespace),
+		client.MatchingLabels{bootstrapv1.NodeConfigSetLabel: set.Name}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list the NodeConfigs of NodeConfigSet %s/%s", set.Namespace, set.Name)
+	}
+	var configs []*bootstrapv1.NodeConfig
+	byAddress := map[string]*bootstrapv1.NodeConfig{}
+	usedIndexes := map[int]bool{}
+	for i := range configList.Items {
+		config := &configList.Items[i]
+		if !metav1.IsControlledBy(config, set) {
+			continue
+		}
+		configs = append(configs, config)
+		if config.Spec.BMC != nil {
+			byAddress[config.Spec.BMC.Address] = config
+		}
+		if index, ok := util.SetNodeConfigIndex(config); ok {
+			usedIndexes[index] = true
+		}
+	}
+
+	tmpl := &bootstrapv1.NodeConfigTemplate{}
+	key := client.ObjectKey{Namespace: set.Namespace, Name: set.Spec.TemplateRef.Name}
+	if err := r.Client.Get(ctx, key, tmpl); err != nil {
+		return configs, errors.Wrapf(err, "failed to get NodeConfigTemplate %s/%s", key.Namespace, key.Name)
+	}
+	hosts, err := util.SetHosts(ctx, r.Client, set)
+	if err != nil {
+		return configs, err
+	}
+
+	// Delete the NodeConfigs of the hosts that are no longer listed
+	listed := map[string]bool{}
+	for i := range hosts {
+		listed[hosts[i].Address] = true
+	}
+	var kept []*bootstrapv1.NodeConfig
+	for _, config := range configs {
+		if config.Spec.BMC != nil && listed[config.Spec.BMC.Address] && byAddress[config.Spec.BMC.Address] == config {
+			kept = append(kept, config)
+			continue
+		}
+		if config.DeletionTimestamp.IsZero() {
+			if err := r.Client.Delete(ctx, config); err != nil && !apierrors.IsNotFound(err) {
+				return configs, errors.Wrapf(err, "failed to delete NodeConfig %s/%s", config.Namespace, config.Name)
+			}
+			r.Recorder.Eventf(set, corev1.EventTypeNormal, "DeletedNodeConfig", "Deleted NodeConfig %s", config.Name)
+		}
+	}
+	configs = kept
+
+	// Create the NodeConfigs of the new hosts
+	index := 0
+	for i := range hosts {
+		host := &hosts[i]
+		if _, ok := byAddress[host.Address]; ok {
+			continue
+		}
+		for usedIndexes[index] {
+			index++
+		}
+		usedIndexes[index] = true
+
+		name, err := util.SetNodeConfigName(set, index)
+		if err != nil {
+			return configs, err
+		}
+		config := util.NewSetNodeConfig(set, tmpl, host, index, name)
+		if err := r.Client.Create(ctx, config); err != nil {
+			return configs, errors.Wrapf(err, "failed to create NodeConfig %s/%s", config.Namespace, config.Name)
+		}
+		r.Recorder.Eventf(set, corev1.EventTypeNormal, "CreatedNodeConfig", "Created NodeConfig %s for BMC %s", config.Name, host.Address)
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+)
+
+func TestNodeConfigSetReconcile(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+
+	tmpl := &bootstrapv1.NodeConfigTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "rack1", Namespace: "default"},
+		Spec: bootstrapv1.NodeConfigTemplateSpec{Template: bootstrapv1.NodeConfigTemplateResource{
+			ObjectMeta: clusterv1.ObjectMeta{Labels: map[string]string{"rack": "1"}},
+			Spec: bootstrapv1.NodeConfigSpec{
+				Metadata: &bootstrapv1.Metadata{Hostname: "rack1-{{ .Index }}"},
+			},
+		}},
+	}
+	inventory := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "rack1-inventory", Namespace: "default"},
+		Data: map[string]string{"hosts": `
+- address: ipmi://192.168.111.3
+  bootMACAddress: 00:5c:52:31:3a:9c
+`},
+	}
+	set := &bootstrapv1.NodeConfigSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "rack1", Namespace: "default", UID: "set-uid"},
+		Spec: bootstrapv1.NodeConfigSetSpec{
+			TemplateRef:          corev1.LocalObjectReference{Name: "rack1"},
+			CredentialsSecretRef: &corev1.LocalObjectReference{Name: "rack1-bmc"},
+			Hosts: []bootstrapv1.BMC{
+				{Address: "ipmi://192.168.111.1"},
+				{Address: "ipmi://192.168.111.2", CredentialsSecretRef: &corev1.LocalObjectReference{Name: "other-bmc"}},
+			},
+			InventoryRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "rack1-inventory"},
+				Key:                  "hosts",
+			},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tmpl, inventory, set).Build()
+	r := &NodeConfigSetReconciler{Client: cl, Recorder: record.NewFakeRecorder(32)}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(set)}
+
+	_, err := r.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	config := &bootstrapv1.NodeConfig{}
+	g.Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "rack1-0"}, config)).To(Succeed())
+	g.Expect(config.Labels).To(Equal(map[string]string{
+		"rack":                         "1",
+		bootstrapv1.NodeConfigSetLabel: "rack1",
+		bootstrapv1.NodeIndexLabel:     "0",
+	}))
+	g.Expect(config.Spec.BMC.Address).To(Equal("ipmi://192.168.111.1"))
+	g.Expect(config.Spec.BMC.CredentialsSecretRef.Name).To(Equal("rack1-bmc"))
+	g.Expect(config.Spec.Metadata.Hostname).To(Equal("rack1-{{ .Index }}"))
+	g.Expect(metav1.IsControlledBy(config, set)).To(BeTrue())
+
+	g.Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "rack1-1"}, config)).To(Succeed())
+	g.Expect(config.Spec.BMC.CredentialsSecretRef.Name).To(Equal("other-bmc"))
+	g.Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "rack1-2"}, config)).To(Succeed())
+	g.Expect(config.Spec.BMC.BootMACAddress).To(Equal("00:5c:52:31:3a:9c"))
+
+	g.Expect(cl.Get(ctx, req.NamespacedName, set)).To(Succeed())
+	g.Expect(set.Status.Replicas).To(BeEquivalentTo(3))
+	g.Expect(conditions.IsTrue(set, bootstrapv1.NodeConfigsCreatedCondition)).To(BeTrue())
+
+	// The last host is provisioned, and the first one replaced by a new one
+	config.Status.Ready = true
+	config.Status.Phase = bootstrapv1.NodeConfigPhaseProvisioned
+	g.Expect(cl.Status().Update(ctx, config)).To(Succeed())
+	set.Spec.Hosts[0] = bootstrapv1.BMC{Address: "ipmi://192.168.111.4"}
+	g.Expect(cl.Update(ctx, set)).To(Succeed())
+
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	configs := &bootstrapv1.NodeConfigList{}
+	g.Expect(cl.List(ctx, configs, client.MatchingLabels{bootstrapv1.NodeConfigSetLabel: "rack1"})).To(Succeed())
+	// The index of the deleted NodeConfig is only reused once it is gone
+	addresses := map[string]string{}
+	for _, c := range configs.Items {
+		addresses[c.Name] = c.Spec.BMC.Address
+	}
+	g.Expect(addresses).To(Equal(map[string]string{
+		"rack1-1": "ipmi://192.168.111.2",
+		"rack1-2": "ipmi://192.168.111.3",
+		"rack1-3": "ipmi://192.168.111.4",
+	}))
+
+	g.Expect(cl.Get(ctx, req.NamespacedName, set)).To(Succeed())
+	g.Expect(set.Status.Replicas).To(BeEquivalentTo(3))
+	g.Expect(set.Status.ReadyReplicas).To(BeEquivalentTo(1))
+	g.Expect(set.Status.ProvisionedReplicas).To(BeEquivalentTo(1))
+
+	// A host listed twice stops the set
+	set.Spec.Hosts = append(set.Spec.Hosts, bootstrapv1.BMC{Address: "ipmi://192.168.111.3"})
+	g.Expect(cl.Update(ctx, set)).To(Succeed())
+	_, err = r.Reconcile(ctx, req)
+	g.Expect(err).To(MatchError(ContainSubstring("listed twice")))
+	g.Expect(cl.Get(ctx, req.NamespacedName, set)).To(Succeed())
+	g.Expect(conditions.GetReason(set, bootstrapv1.NodeConfigsCreatedCondition)).To(Equal(bootstrapv1.NodeConfigsCreateFailedReason))
+	g.Expect(set.Status.Replicas).To(BeEquivalentTo(3))
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package utils contains Kubeadm utility types.
+package utils
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/tmax-cloud/nodeconfig-operator/types/v1beta1"
+	"github.com/tmax-cloud/nodeconfig-operator/types/v1beta2"
+	"github.com/tmax-cloud/nodeconfig-operator/types/v1beta3"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	v1beta1KubeadmVersion = version.MustParseSemantic("1.13.0")
+	v1beta2KubeadmVersion = version.MustParseSemantic("1.15.0")
+	v1beta3KubeadmVersion = version.MustParseSemantic("1.22.0")
+
+	clusterConfigurationVersionTypeMap = map[schema.GroupVersion]runtime.Object{
+		v1beta3.GroupVersion: &v1beta3.ClusterConfiguration{},
+		v1beta2.GroupVersion: &v1beta2.ClusterConfiguration{},
+		v1beta1.GroupVersion: &v1beta1.ClusterConfiguration{},
+	}
+
+	initConfigurationVersionTypeMap = map[schema.GroupVersion]runtime.Object{
+		v1beta3.GroupVersion: &v1beta3.InitConfiguration{},
+		v1beta2.GroupVersion: &v1beta2.InitConfiguration{},
+		v1beta1.GroupVersion: &v1beta1.InitConfiguration{},
+	}
+
+	joinConfigurationVersionTypeMap = map[schema.GroupVersion]runtime.Object{
+		v1beta3.GroupVersion: &v1beta3.JoinConfiguration{},
+		v1beta2.GroupVersion: &v1beta2.JoinConfiguration{},
+		v1beta1.GroupVersion: &v1beta1.JoinConfiguration{},
+	}
+)
+
+// KubeVersionToKubeadmAPIGroupVersion maps a Kubernetes version to the correct Kubeadm API Group supported.
+func KubeVersionToKubeadmAPIGroupVersion(kubernetesVersion string) (schema.GroupVersion, error) {
+	v, err := version.ParseSemantic(kubernetesVersion)
+	if err != nil {
+		return schema.GroupVersion{}, errors.Wrapf(err, "invalid Kubernetes version %q", kubernetesVersion)
+	}
+
+	switch {
+	case v.LessThan(v1beta1KubeadmVersion):
+		return schema.GroupVersion{}, errors.New("Kubernetes versions lower than v1.13.0 are not supported")
+	case v.LessThan(v1beta2KubeadmVersion):
+		// NOTE: All the Kubernetes version >= v1.13 and < v1.15 should use the kubeadm API version v1beta1
+		return v1beta1.GroupVersion, nil
+	case v.LessThan(v1beta3KubeadmVersion):
+		// NOTE: All the Kubernetes version >= v1.15 and < v1.22 should use the kubeadm API version v1beta2
+		return v1beta2.GroupVersion, nil
+	default:
+		// NOTE: All the Kubernetes version greater or equal to v1.22 should use the kubeadm API version v1beta3,
+		// including future releases that kubeadm still reads v1beta3 for.
+		return v1beta3.GroupVersion, nil
+	}
+}
+
+// MarshalClusterConfigurationForVersion converts a v1beta2 ClusterConfiguration to the kubeadm API type
+// for the given Kubernetes version, and returns its YAML representation.
+// NOTE: This assumes Kubernetes Version equals to kubeadm version.
+func MarshalClusterConfigurationForVersion(obj *v1beta2.ClusterConfiguration, kubernetesVersion string) (string, error) {
+	return marshalForVersion(obj, kubernetesVersion, clusterConfigurationVersionTypeMap)
+}
+
+// MarshalInitConfigurationForVersion converts a v1beta2 InitConfiguration to the kubeadm API type
+// for the given Kubernetes version, and returns its YAML representation.
+// NOTE: This assumes Kubernetes Version equals to kubeadm version.
+func MarshalInitConfigurationForVersion(obj *v1beta2.InitConfiguration, kubernetesVersion string) (string, error) {
+	return marshalForVersion(obj, kubernetesVersion, initConfigurationVersionTypeMap)
+}
+
+// MarshalJoinConfigurationForVersion converts a v1beta2 JoinConfiguration to the kubeadm API type
+// for the given Kubernetes version, and returns its YAML representation.
+// NOTE: This assumes Kubernetes Version equals to kubeadm version.
+func MarshalJoinConfigurationForVersion(obj *v1beta2.JoinConfiguration, kubernetesVersion string) (string, error) {
+	return marshalForVersion(obj, kubernetesVersion, joinConfigurationVersionTypeMap)
+}
+
+func marshalForVersion(obj runtime.Object, kubernetesVersion string, kubeadmObjVersionTypeMap map[schema.GroupVersion]runtime.Object) (string, error) {
+	kubeadmAPIGroupVersion, err := KubeVersionToKubeadmAPIGroupVersion(kubernetesVersion)
+	if err != nil {
+		return "", err
+	}
+
+	targetKubeadmObj, ok := kubeadmObjVersionTypeMap[kubeadmAPIGroupVersion]
+	if !ok {
+		return "", errors.Errorf("missing KubeadmAPI type mapping for version %s", kubeadmAPIGroupVersion)
+	}
+
+	targetKubeadmObj = targetKubeadmObj.DeepCopyObject()
+	if err := convert(obj, targetKubeadmObj); err != nil {
+		return "", errors.Wrapf(err, "failed to convert to KubeadmAPI type for version %s", kubeadmAPIGroupVersion)
+	}
+
+	codecs, err := getCodecsFor(kubeadmAPIGroupVersion, targetKubeadmObj)
+	if err != nil {
+		return "", err
+	}
+
+	yaml, err := v1beta2.MarshalToYamlForCodecs(targetKubeadmObj, kubeadmAPIGroupVersion, codecs)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate yaml for the Kubeadm API for version %s", kubeadmAPIGroupVersion)
+	}
+	return string(yaml), nil
+}
+
+// convert copies the fields of the v1beta2 object to the object of another
+// kubeadm API version. The kubeadm API versions differ in added and removed
+// fields only, so the fields the target version does not know are dropped.
+func convert(in, out runtime.Object) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return err
+	}
+	// The target version is set by the encoder
+	out.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+	return nil
+}
+
+func getCodecsFor(gv schema.GroupVersion, obj runtime.Object) (serializer.CodecFactory, error) {
+	sb := &scheme.Builder{GroupVersion: gv}
+	sb.Register(obj)
+	kubeadmScheme, err := sb.Build()
+	if err != nil {
+		return serializer.CodecFactory{}, errors.Wrapf(err, "failed to build scheme for kubeadm types conversions")
+	}
+	return serializer.NewCodecFactory(kubeadmScheme), nil
+}
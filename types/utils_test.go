@@ -0,0 +1,144 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/tmax-cloud/nodeconfig-operator/types/v1beta1"
+	"github.com/tmax-cloud/nodeconfig-operator/types/v1beta2"
+	"github.com/tmax-cloud/nodeconfig-operator/types/v1beta3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestKubeVersionToKubeadmAPIGroupVersion(t *testing.T) {
+	var tests = []struct {
+		name    string
+		version string
+		want    schema.GroupVersion
+		wantErr bool
+	}{
+		{name: "invalid", version: "latest", wantErr: true},
+		{name: "pre 1.13", version: "v1.12.5", wantErr: true},
+		{name: "1.13", version: "v1.13.0", want: v1beta1.GroupVersion},
+		{name: "1.14 without v", version: "1.14.9", want: v1beta1.GroupVersion},
+		{name: "1.15", version: "v1.15.0", want: v1beta2.GroupVersion},
+		{name: "1.21", version: "v1.21.2", want: v1beta2.GroupVersion},
+		{name: "1.22", version: "v1.22.0", want: v1beta3.GroupVersion},
+		{name: "future", version: "v1.30.1", want: v1beta3.GroupVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, err := KubeVersionToKubeadmAPIGroupVersion(tt.version)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestMarshalClusterConfigurationForVersion(t *testing.T) {
+	obj := &v1beta2.ClusterConfiguration{
+		KubernetesVersion: "v1.21.2",
+		UseHyperKubeImage: true,
+		DNS:               v1beta2.DNS{Type: v1beta2.CoreDNS},
+	}
+
+	var tests = []struct {
+		name    string
+		version string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "v1beta1",
+			version: "v1.14.0",
+			want:    []string{"apiVersion: kubeadm.k8s.io/v1beta1", "kind: ClusterConfiguration", "useHyperKubeImage: true"},
+		},
+		{
+			name:    "v1beta2",
+			version: "v1.21.2",
+			want:    []string{"apiVersion: kubeadm.k8s.io/v1beta2", "useHyperKubeImage: true", "type: CoreDNS"},
+		},
+		{
+			name:    "v1beta3 drops the removed fields",
+			version: "v1.22.0",
+			want:    []string{"apiVersion: kubeadm.k8s.io/v1beta3", "kind: ClusterConfiguration", "kubernetesVersion: v1.21.2"},
+			notWant: []string{"useHyperKubeImage", "type: CoreDNS"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			yaml, err := MarshalClusterConfigurationForVersion(obj, tt.version)
+			g.Expect(err).NotTo(HaveOccurred())
+			for _, s := range tt.want {
+				g.Expect(yaml).To(ContainSubstring(s))
+			}
+			for _, s := range tt.notWant {
+				g.Expect(yaml).NotTo(ContainSubstring(s))
+			}
+		})
+	}
+}
+
+func TestMarshalInitConfigurationForVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &v1beta2.InitConfiguration{CertificateKey: "abc"}
+
+	yaml, err := MarshalInitConfigurationForVersion(obj, "v1.14.0")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(yaml).To(ContainSubstring("apiVersion: kubeadm.k8s.io/v1beta1"))
+	g.Expect(yaml).To(ContainSubstring("kind: InitConfiguration"))
+	g.Expect(yaml).NotTo(ContainSubstring("certificateKey"))
+
+	yaml, err = MarshalInitConfigurationForVersion(obj, "v1.22.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(yaml).To(ContainSubstring("apiVersion: kubeadm.k8s.io/v1beta3"))
+	g.Expect(yaml).To(ContainSubstring("certificateKey: abc"))
+}
+
+func TestMarshalJoinConfigurationForVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &v1beta2.JoinConfiguration{
+		Discovery: v1beta2.Discovery{
+			BootstrapToken: &v1beta2.BootstrapTokenDiscovery{
+				Token:             "abcdef.0123456789abcdef",
+				APIServerEndpoint: "192.168.0.10:6443",
+			},
+		},
+	}
+
+	yaml, err := MarshalJoinConfigurationForVersion(obj, "v1.22.1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(yaml).To(ContainSubstring("apiVersion: kubeadm.k8s.io/v1beta3"))
+	g.Expect(yaml).To(ContainSubstring("kind: JoinConfiguration"))
+	g.Expect(yaml).To(ContainSubstring("token: abcdef.0123456789abcdef"))
+	g.Expect(yaml).To(ContainSubstring("apiServerEndpoint: 192.168.0.10:6443"))
+
+	_, err = MarshalJoinConfigurationForVersion(obj, "v1.12.0")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -86,3 +86,12 @@ func NewBootstrapTokenString(token string) (*BootstrapTokenString, error) {
 func NewBootstrapTokenStringFromIDAndSecret(id, secret string) (*BootstrapTokenString, error) {
 	return NewBootstrapTokenString(bootstraputil.TokenFromIDAndSecret(id, secret))
 }
+
+// GenerateBootstrapTokenString returns a new random BootstrapTokenString
+func GenerateBootstrapTokenString() (*BootstrapTokenString, error) {
+	token, err := bootstraputil.GenerateBootstrapToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate bootstrap token")
+	}
+	return NewBootstrapTokenString(token)
+}
@@ -221,3 +221,20 @@ func TestNewBootstrapTokenStringFromIDAndSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateBootstrapTokenString(t *testing.T) {
+	g := NewWithT(t)
+
+	bts, err := GenerateBootstrapTokenString()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(bts.ID).To(HaveLen(6))
+	g.Expect(bts.Secret).To(HaveLen(16))
+
+	parsed, err := NewBootstrapTokenString(bts.String())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(parsed).To(Equal(bts))
+
+	other, err := GenerateBootstrapTokenString()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(other.String()).NotTo(Equal(bts.String()))
+}
@@ -14,13 +14,13 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package cloudinit
+package v1beta2
 
-const (
-	commandsTemplate = `{{- define "commands" -}}
-{{ range . }}
-  - {{printf "%q" .}}
-{{- end -}}
-{{- end -}}
-`
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// GroupVersion is group version used to register these objects
+	GroupVersion = schema.GroupVersion{Group: "kubeadm.k8s.io", Version: "v1beta2"}
 )
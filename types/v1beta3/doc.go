@@ -0,0 +1,42 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +k8s:defaulter-gen=TypeMeta
+// +groupName=kubeadm.k8s.io
+// +k8s:deepcopy-gen=package
+// +k8s:conversion-gen=k8s.io/kubernetes/cmd/kubeadm/app/apis/kubeadm
+
+// Package v1beta3 defines the v1beta3 version of the kubeadm configuration file format.
+// This version improves on the v1beta2 format by fixing some minor issues and adding a few new fields.
+//
+// A list of changes since v1beta2:
+//	- The deprecated "ClusterConfiguration.useHyperKubeImage" field has been removed.
+//	Kubeadm no longer supports the hyperkube image.
+//	- The "ClusterConfiguration.DNS.Type" field has been removed since CoreDNS is the only DNS server type by kubeadm.
+//	- Include "datapolicy" tags on the fields that hold secrets.
+//	This would result in the field values to be omitted when API structures are printed with klog.
+//	- Add "InitConfiguration.SkipPhases", "JoinConfiguration.SkipPhases" to allow skipping
+//	a list of phases during kubeadm init/join command execution.
+//	See the Kubernetes 1.22 changelog for further details.
+//
+// Migration from old kubeadm config versions
+//
+// Please convert your v1beta2 configuration files to v1beta3 using the "kubeadm config migrate" command of kubeadm v1.22.x
+//
+// Nevertheless, kubeadm v1.22.x will support reading from v1beta2 version of the kubeadm config file format.
+//
+
+package v1beta3
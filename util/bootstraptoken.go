@@ -0,0 +1,218 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	kubeadmv1beta2 "github.com/tmax-cloud/nodeconfig-operator/types/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// bootstrapTokenKey is the key of the user data secret that keeps the
+	// bootstrap token rendered into the user data, so re-rendering the user
+	// data does not change it.
+	bootstrapTokenKey = "bootstrapToken"
+
+	// bootstrapTokenGroup is the group kubeadm grants to node bootstrap tokens.
+	bootstrapTokenGroup = "system:bootstrappers:kubeadm:default-node-token"
+)
+
+// bootstrapToken returns the bootstrap token to render into the
+// JoinConfiguration. The token already rendered is kept while it is valid,
+// once the node has registered or once the host has been provisioned with
+// it. Otherwise the token of the status is reused while it is valid, and a
+// new one is generated only when there is none.
+func (c *ConfigManager) bootstrapToken(ctx context.Context) (string, error) {
+	current, err := c.storedBootstrapToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	// The node has joined, so the token is not needed anymore
+	if c.NodeConfig.Status.NodeName != "" {
+		return current, nil
+	}
+	// A new token would change the user data of the provisioned host, which
+	// the reprovision policy may act on
+	if current != "" && c.NodeConfig.Status.ProvisionedUserDataHash != "" &&
+		conditions.IsTrue(c.NodeConfig, bootstrapv1.ProvisionedCondition) {
+		return current, nil
+	}
+
+	if bts, err := kubeadmv1beta2.NewBootstrapTokenString(current); err == nil {
+		secret, err := c.getBootstrapTokenSecret(ctx, bts.ID)
+		if err != nil {
+			return "", err
+		}
+		if secret != nil && !isBootstrapTokenExpired(secret) {
+			c.NodeConfig.Status.BootstrapTokenID = bts.ID
+			return current, nil
+		}
+	}
+
+	// A token created by a reconcile that failed before storing the user
+	// data is still valid, so it is used rather than minting another one
+	if tokenID := c.NodeConfig.Status.BootstrapTokenID; tokenID != "" {
+		secret, err := c.getBootstrapTokenSecret(ctx, tokenID)
+		if err != nil {
+			return "", err
+		}
+		if secret != nil && !isBootstrapTokenExpired(secret) {
+			bts, err := kubeadmv1beta2.NewBootstrapTokenString(bootstraputil.TokenFromIDAndSecret(
+				string(secret.Data[bootstrapapi.BootstrapTokenIDKey]),
+				string(secret.Data[bootstrapapi.BootstrapTokenSecretKey])))
+			if err == nil {
+				return bts.String(), nil
+			}
+		}
+	}
+
+	return c.createBootstrapToken(ctx)
+}
+
+// storedBootstrapToken returns the token kept in the user data secret.
+func (c *ConfigManager) storedBootstrapToken(ctx context.Context) (string, error) {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: c.NodeConfig.Namespace, Name: c.NodeConfig.Name}
+	if err := c.client.Get(ctx, key, secret); apierrors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Wrapf(err, "failed to get bootstrap data secret for NodeConfig %s/%s", c.NodeConfig.Namespace, c.NodeConfig.Name)
+	}
+	return string(secret.Data[bootstrapTokenKey]), nil
+}
+
+// createBootstrapToken generates a bootstrap token and creates its secret in
+// kube-system.
+func (c *ConfigManager) createBootstrapToken(ctx context.Context) (string, error) {
+	bts, err := kubeadmv1beta2.GenerateBootstrapTokenString()
+	if err != nil {
+		return "", err
+	}
+
+	ttl := c.NodeConfig.BootstrapTokenTTL()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bootstraputil.BootstrapTokenSecretName(bts.ID),
+			Namespace: metav1.NamespaceSystem,
+		},
+		Type: bootstrapapi.SecretTypeBootstrapToken,
+		Data: map[string][]byte{
+			bootstrapapi.BootstrapTokenIDKey:               []byte(bts.ID),
+			bootstrapapi.BootstrapTokenSecretKey:           []byte(bts.Secret),
+			bootstrapapi.BootstrapTokenExpirationKey:       []byte(time.Now().UTC().Add(ttl).Format(time.RFC3339)),
+			bootstrapapi.BootstrapTokenUsageAuthentication: []byte("true"),
+			bootstrapapi.BootstrapTokenUsageSigningKey:     []byte("true"),
+			bootstrapapi.BootstrapTokenExtraGroupsKey:      []byte(bootstrapTokenGroup),
+			bootstrapapi.BootstrapTokenDescriptionKey: []byte(fmt.Sprintf("Bootstrap token for NodeConfig %s/%s",
+				c.NodeConfig.Namespace, c.NodeConfig.Name)),
+		},
+	}
+	if err := c.client.Create(ctx, secret); err != nil {
+		return "", errors.Wrapf(err, "failed to create bootstrap token secret for NodeConfig %s/%s", c.NodeConfig.Namespace, c.NodeConfig.Name)
+	}
+
+	c.Log.Info("Created a bootstrap token", "tokenID", bts.ID, "ttl", ttl)
+	c.NodeConfig.Status.BootstrapTokenID = bts.ID
+	return bts.String(), nil
+}
+
+// ReconcileBootstrapToken revokes the bootstrap token of the NodeConfig once
+// its Node has registered or the token has expired. It returns how long the
+// token is still valid, to check on it again by then.
+func (c *ConfigManager) ReconcileBootstrapToken(ctx context.Context) (time.Duration, error) {
+	tokenID := c.NodeConfig.Status.BootstrapTokenID
+	if tokenID == "" {
+		return 0, nil
+	}
+
+	node := &corev1.Node{}
+	nodeName := NodeName(c.NodeConfig)
+	if err := c.client.Get(ctx, client.ObjectKey{Name: nodeName}, node); err == nil {
+		c.Log.Info("The node has registered. Revoke its bootstrap token", "node", node.Name, "tokenID", tokenID)
+		c.NodeConfig.Status.NodeName = node.Name
+		return 0, c.RevokeBootstrapToken(ctx)
+	} else if !apierrors.IsNotFound(err) {
+		return 0, errors.Wrapf(err, "failed to get the node %s", nodeName)
+	}
+
+	secret, err := c.getBootstrapTokenSecret(ctx, tokenID)
+	if err != nil {
+		return 0, err
+	}
+	if secret == nil || isBootstrapTokenExpired(secret) {
+		c.Log.Info("The bootstrap token has expired. Revoke it", "tokenID", tokenID)
+		return 0, c.RevokeBootstrapToken(ctx)
+	}
+	return bootstrapTokenTTL(secret), nil
+}
+
+// RevokeBootstrapToken deletes the bootstrap token secret of the NodeConfig.
+func (c *ConfigManager) RevokeBootstrapToken(ctx context.Context) error {
+	tokenID := c.NodeConfig.Status.BootstrapTokenID
+	if tokenID == "" {
+		return nil
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bootstraputil.BootstrapTokenSecretName(tokenID),
+			Namespace: metav1.NamespaceSystem,
+		},
+	}
+	if err := c.client.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to revoke bootstrap token %s", tokenID)
+	}
+	c.NodeConfig.Status.BootstrapTokenID = ""
+	return nil
+}
+
+// getBootstrapTokenSecret returns the secret of the token, or nil if it is gone.
+func (c *ConfigManager) getBootstrapTokenSecret(ctx context.Context, tokenID string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: metav1.NamespaceSystem, Name: bootstraputil.BootstrapTokenSecretName(tokenID)}
+	if err := c.client.Get(ctx, key, secret); apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to get bootstrap token %s", tokenID)
+	}
+	return secret, nil
+}
+
+// bootstrapTokenTTL returns how long the token secret is still valid.
+func bootstrapTokenTTL(secret *corev1.Secret) time.Duration {
+	expiration, err := time.Parse(time.RFC3339, string(secret.Data[bootstrapapi.BootstrapTokenExpirationKey]))
+	if err != nil {
+		return 0
+	}
+	return time.Until(expiration)
+}
+
+func isBootstrapTokenExpired(secret *corev1.Secret) bool {
+	return bootstrapTokenTTL(secret) <= 0
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	kubeadmv1beta2 "github.com/tmax-cloud/nodeconfig-operator/types/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha4"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestBootstrapToken(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	NewWithT(t).Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+
+	newConfigManager := func(objs ...client.Object) *ConfigManager {
+		config := &bootstrapv1.NodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default"},
+			Spec: bootstrapv1.NodeConfigSpec{
+				Kubeadm: &bootstrapv1.KubeadmSpec{JoinConfiguration: &kubeadmv1beta2.JoinConfiguration{}},
+			},
+		}
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		return &ConfigManager{client: cl, NodeConfig: config, Log: log.Log}
+	}
+	// storeToken keeps the token in the user data secret, as rendering does
+	storeToken := func(g *WithT, c *ConfigManager, token string) {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: c.NodeConfig.Name, Namespace: c.NodeConfig.Namespace},
+			Data:       map[string][]byte{bootstrapTokenKey: []byte(token)},
+		}
+		g.Expect(c.client.Create(ctx, secret)).To(Succeed())
+	}
+	// expireToken moves the expiration of the token secret into the past
+	expireToken := func(g *WithT, c *ConfigManager, tokenID string) {
+		secret, err := c.getBootstrapTokenSecret(ctx, tokenID)
+		g.Expect(err).NotTo(HaveOccurred())
+		secret.Data[bootstrapapi.BootstrapTokenExpirationKey] = []byte(time.Now().UTC().Add(-time.Minute).Format(time.RFC3339))
+		g.Expect(c.client.Update(ctx, secret)).To(Succeed())
+	}
+	tokenID := func(g *WithT, token string) string {
+		bts, err := kubeadmv1beta2.NewBootstrapTokenString(token)
+		g.Expect(err).NotTo(HaveOccurred())
+		return bts.ID
+	}
+
+	t.Run("creates a token", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := newConfigManager()
+		token, err := c.bootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		id := tokenID(g, token)
+		g.Expect(c.NodeConfig.Status.BootstrapTokenID).To(Equal(id))
+
+		secret, err := c.getBootstrapTokenSecret(ctx, id)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(secret.Type).To(Equal(corev1.SecretType(bootstrapapi.SecretTypeBootstrapToken)))
+		g.Expect(secret.Data[bootstrapapi.BootstrapTokenExtraGroupsKey]).To(BeEquivalentTo(bootstrapTokenGroup))
+		ttl := bootstrapTokenTTL(secret)
+		g.Expect(ttl).To(BeNumerically(">", bootstrapv1.DefaultBootstrapTokenTTL-time.Minute))
+		g.Expect(ttl).To(BeNumerically("<=", bootstrapv1.DefaultBootstrapTokenTTL))
+	})
+
+	t.Run("reuses a valid token", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := newConfigManager()
+		token, err := c.createBootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		storeToken(g, c, token)
+		c.NodeConfig.Status.BootstrapTokenID = ""
+
+		reused, err := c.bootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(reused).To(Equal(token))
+		g.Expect(c.NodeConfig.Status.BootstrapTokenID).To(Equal(tokenID(g, token)))
+
+		secrets := &corev1.SecretList{}
+		g.Expect(c.client.List(ctx, secrets, client.InNamespace(metav1.NamespaceSystem))).To(Succeed())
+		g.Expect(secrets.Items).To(HaveLen(1))
+	})
+
+	t.Run("reuses the token of a failed render", func(t *testing.T) {
+		g := NewWithT(t)
+
+		// The first render created a token but failed before storing the
+		// user data
+		c := newConfigManager()
+		token, err := c.bootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		again, err := c.bootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(again).To(Equal(token))
+		secrets := &corev1.SecretList{}
+		g.Expect(c.client.List(ctx, secrets, client.InNamespace(metav1.NamespaceSystem))).To(Succeed())
+		g.Expect(secrets.Items).To(HaveLen(1))
+
+		// An expired one is replaced
+		expireToken(g, c, tokenID(g, token))
+		replaced, err := c.bootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(replaced).NotTo(Equal(token))
+	})
+
+	t.Run("replaces an expired token", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := newConfigManager()
+		token, err := c.createBootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		storeToken(g, c, token)
+		expireToken(g, c, tokenID(g, token))
+
+		replaced, err := c.bootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(replaced).NotTo(Equal(token))
+		g.Expect(c.NodeConfig.Status.BootstrapTokenID).To(Equal(tokenID(g, replaced)))
+	})
+
+	t.Run("keeps the token of a provisioned host", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := newConfigManager()
+		token, err := c.createBootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		storeToken(g, c, token)
+		expireToken(g, c, tokenID(g, token))
+		c.NodeConfig.Status.ProvisionedUserDataHash = "hash"
+		conditions.MarkTrue(c.NodeConfig, bootstrapv1.ProvisionedCondition)
+
+		kept, err := c.bootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(kept).To(Equal(token))
+
+		// A host still provisioning gets a new one
+		conditions.MarkFalse(c.NodeConfig, bootstrapv1.ProvisionedCondition,
+			bootstrapv1.WaitingForProvisioningReason, clusterv1.ConditionSeverityInfo, "")
+		replaced, err := c.bootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(replaced).NotTo(Equal(token))
+	})
+
+	t.Run("revokes the token once the node registers", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := newConfigManager(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "host-4"}})
+		c.NodeConfig.Status.HostRef = &corev1.ObjectReference{Name: "host-4"}
+		_, err := c.bootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		id := c.NodeConfig.Status.BootstrapTokenID
+
+		requeue, err := c.ReconcileBootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(requeue).To(BeZero())
+		g.Expect(c.NodeConfig.Status.NodeName).To(Equal("host-4"))
+		g.Expect(c.NodeConfig.Status.BootstrapTokenID).To(BeEmpty())
+		secret := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: metav1.NamespaceSystem, Name: bootstraputil.BootstrapTokenSecretName(id)}
+		g.Expect(apierrors.IsNotFound(c.client.Get(ctx, key, secret))).To(BeTrue())
+	})
+
+	t.Run("revokes the token once it expires", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := newConfigManager()
+		_, err := c.bootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		id := c.NodeConfig.Status.BootstrapTokenID
+
+		// A valid token is checked on again when it expires
+		requeue, err := c.ReconcileBootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(requeue).To(BeNumerically(">", 0))
+		g.Expect(c.NodeConfig.Status.BootstrapTokenID).To(Equal(id))
+
+		expireToken(g, c, id)
+		_, err = c.ReconcileBootstrapToken(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(c.NodeConfig.Status.NodeName).To(BeEmpty())
+		g.Expect(c.NodeConfig.Status.BootstrapTokenID).To(BeEmpty())
+		secret, err := c.getBootstrapTokenSecret(ctx, id)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(secret).To(BeNil())
+	})
+}
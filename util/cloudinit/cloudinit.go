@@ -17,17 +17,21 @@ limitations under the License.
 package cloudinit
 
 import (
-	"bytes"
-	"text/template"
-
 	"github.com/pkg/errors"
 	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	"gopkg.in/yaml.v2"
 )
 
 const (
-	cloudConfigHeader = `## template: jinja
-#cloud-config
+	// cloudConfigHeader marks plain cloud-config. It is not a Jinja template,
+	// so braces in user supplied values reach the node as they are.
+	cloudConfigHeader = `#cloud-config
 `
+
+	// kubeadmConfigOwner and kubeadmConfigPermissions are set on the kubeadm
+	// configuration files written to the node.
+	kubeadmConfigOwner       = "root:root"
+	kubeadmConfigPermissions = "0640"
 )
 
 // BaseUserData is shared across all the various types of files written to disk.
@@ -38,35 +42,58 @@ type BaseUserData struct {
 	WriteFiles        []bootstrapv1.File
 	Users             []bootstrapv1.User
 	NTP               *bootstrapv1.NTP
+	Disks             []bootstrapv1.Disk
+	VolumeGroups      []bootstrapv1.VolumeGroup
+	Filesystems       []bootstrapv1.Filesystem
+	Mounts            []bootstrapv1.Mount
+	Packages          *bootstrapv1.Packages
+	PackageKeys       PackageKeys
 }
 
-func generate(kind string, tpl string, data interface{}) ([]byte, error) {
-	tm := template.New(kind).Funcs(defaultTemplateFuncMap)
-	if _, err := tm.Parse(filesTemplate); err != nil {
-		return nil, errors.Wrap(err, "failed to parse files template")
+// cloudConfig returns the cloud config of the files, commands, NTP, users,
+// storage and packages shared by all the user data.
+func (input *BaseUserData) cloudConfig() *CloudConfig {
+	config := &CloudConfig{
+		BootCmd: lvmCommands(input.VolumeGroups),
+		RunCmd:  append([]string{}, input.CloudInitCommands...),
+		NTP:     convertNTP(input.NTP),
 	}
-
-	if _, err := tm.Parse(commandsTemplate); err != nil {
-		return nil, errors.Wrap(err, "failed to parse commands template")
+	for _, d := range input.Disks {
+		if config.DiskSetup == nil {
+			config.DiskSetup = map[string]DiskSetup{}
+		}
+		config.DiskSetup[d.Device] = convertDisk(d)
 	}
-
-	if _, err := tm.Parse(ntpTemplate); err != nil {
-		return nil, errors.Wrap(err, "failed to parse ntp template")
+	for _, f := range input.Filesystems {
+		config.FSSetup = append(config.FSSetup, convertFilesystem(f))
 	}
-
-	if _, err := tm.Parse(usersTemplate); err != nil {
-		return nil, errors.Wrap(err, "failed to parse users template")
+	for _, m := range input.Mounts {
+		config.Mounts = append(config.Mounts, convertMount(m))
 	}
-
-	t, err := tm.Parse(tpl)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to parse %s template", kind)
+	for _, f := range input.WriteFiles {
+		config.WriteFiles = append(config.WriteFiles, convertFile(f))
+	}
+	for _, u := range input.Users {
+		config.Users = append(config.Users, convertUser(u))
 	}
+	addPackages(config, input.Packages, input.PackageKeys)
+	return config
+}
 
-	var out bytes.Buffer
-	if err := t.Execute(&out, data); err != nil {
-		return nil, errors.Wrapf(err, "failed to generate %s template", kind)
+// kubeadmConfigFile returns the file a kubeadm configuration is written to.
+func kubeadmConfigFile(path string, content string) File {
+	return File{
+		Path:        path,
+		Owner:       kubeadmConfigOwner,
+		Permissions: kubeadmConfigPermissions,
+		Content:     content,
 	}
+}
 
-	return out.Bytes(), nil
+func generate(kind string, header string, config *CloudConfig) ([]byte, error) {
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal %s cloud config", kind)
+	}
+	return append([]byte(header), out...), nil
 }
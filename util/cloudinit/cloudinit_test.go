@@ -17,13 +17,41 @@ limitations under the License.
 package cloudinit
 
 import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
 
 	infrav1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
 )
 
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// parse reads the rendered user data back, checking its header.
+func parse(g *WithT, out []byte) CloudConfig {
+	g.Expect(string(out)).To(HavePrefix(cloudConfigHeader))
+
+	config := CloudConfig{}
+	g.Expect(yaml.UnmarshalStrict(out, &config)).To(Succeed())
+	return config
+}
+
+// expectGolden compares the rendered user data to testdata/<name>.yaml.
+func expectGolden(g *WithT, name string, out []byte) {
+	golden := filepath.Join("testdata", name+".yaml")
+	if *update {
+		g.Expect(ioutil.WriteFile(golden, out, 0644)).To(Succeed())
+	}
+	expected, err := ioutil.ReadFile(golden)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(out)).To(Equal(string(expected)))
+}
+
 func TestNewNodeAdditionalFileEncodings(t *testing.T) {
 	g := NewWithT(t)
 
@@ -51,18 +79,11 @@ func TestNewNodeAdditionalFileEncodings(t *testing.T) {
 	out, err := NewNode(nodeinput)
 	g.Expect(err).NotTo(HaveOccurred())
 
-	expectedFiles := []string{
-		`-   path: /tmp/my-path
-    encoding: "base64"
-    content: |
-      aGk=`,
-		`-   path: /tmp/my-other-path
-    content: |
-      hi`,
-	}
-	for _, f := range expectedFiles {
-		g.Expect(out).To(ContainSubstring(f))
-	}
+	config := parse(g, out)
+	g.Expect(config.WriteFiles).To(Equal([]File{
+		{Path: "/tmp/my-path", Encoding: "base64", Content: "aGk="},
+		{Path: "/tmp/my-other-path", Content: "hi"},
+	}))
 }
 
 func TestNewNodeCommands(t *testing.T) {
@@ -82,10 +103,293 @@ func TestNewNodeCommands(t *testing.T) {
 	out, err := NewNode(nodeinput)
 	g.Expect(err).NotTo(HaveOccurred())
 
-	expectedCommands := []string{
-		`"\"echo $(date) ': hello world!'\""`,
+	config := parse(g, out)
+	g.Expect(config.RunCmd).To(Equal([]string{`"echo $(date) ': hello world!'"`}))
+}
+
+func TestNewNodeJoinConfiguration(t *testing.T) {
+	g := NewWithT(t)
+
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			CloudInitCommands: []string{"yum install -y kubeadm"},
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(out).NotTo(ContainSubstring(KubeadmJoinConfigPath))
+
+	out, err = NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			CloudInitCommands: []string{"yum install -y kubeadm"},
+		},
+		JoinConfiguration: "apiVersion: kubeadm.k8s.io/v1beta2\nkind: JoinConfiguration",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	config := parse(g, out)
+	g.Expect(config.WriteFiles).To(Equal([]File{
+		{
+			Path:        "/tmp/kubeadm-node.yaml",
+			Owner:       "root:root",
+			Permissions: "0640",
+			Content:     "---\napiVersion: kubeadm.k8s.io/v1beta2\nkind: JoinConfiguration",
+		},
+	}))
+	g.Expect(config.RunCmd).To(Equal([]string{"yum install -y kubeadm", "kubeadm join --config /tmp/kubeadm-node.yaml"}))
+}
+
+func TestNewInitControlPlane(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := NewInitControlPlane(&ControlPlaneInput{})
+	g.Expect(err).To(HaveOccurred())
+
+	out, err := NewInitControlPlane(&ControlPlaneInput{
+		ClusterConfiguration: "apiVersion: kubeadm.k8s.io/v1beta2\nkind: ClusterConfiguration",
+		InitConfiguration:    "apiVersion: kubeadm.k8s.io/v1beta2\nkind: InitConfiguration",
+		UploadCerts:          true,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	config := parse(g, out)
+	g.Expect(config.WriteFiles).To(Equal([]File{
+		{
+			Path:        "/tmp/kubeadm.yaml",
+			Owner:       "root:root",
+			Permissions: "0640",
+			Content: "---\napiVersion: kubeadm.k8s.io/v1beta2\nkind: ClusterConfiguration\n" +
+				"---\napiVersion: kubeadm.k8s.io/v1beta2\nkind: InitConfiguration",
+		},
+	}))
+	g.Expect(config.RunCmd).To(Equal([]string{"kubeadm init --config /tmp/kubeadm.yaml --upload-certs"}))
+}
+
+func TestNewJoinControlPlane(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := NewJoinControlPlane(&ControlPlaneJoinInput{})
+	g.Expect(err).To(HaveOccurred())
+
+	out, err := NewJoinControlPlane(&ControlPlaneJoinInput{
+		JoinConfiguration: "apiVersion: kubeadm.k8s.io/v1beta2\nkind: JoinConfiguration",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	config := parse(g, out)
+	g.Expect(config.RunCmd).To(Equal([]string{"kubeadm join --config /tmp/kubeadm-node.yaml"}))
+}
+
+func TestNewNodeGolden(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input *NodeInput
+	}{
+		{
+			name: "node",
+			input: &NodeInput{
+				BaseUserData: BaseUserData{
+					CloudInitCommands: []string{"yum install -y kubeadm", "systemctl enable --now kubelet"},
+					AdditionalFiles: []infrav1.File{
+						{
+							Path:        "/etc/sysctl.d/k8s.conf",
+							Owner:       "root:root",
+							Permissions: "0644",
+							Content:     "net.ipv4.ip_forward = 1\n",
+						},
+					},
+					Users: []infrav1.User{
+						{
+							Name:              "tmax",
+							Groups:            pointer.StringPtr("wheel"),
+							Sudo:              pointer.StringPtr("ALL=(ALL) NOPASSWD:ALL"),
+							LockPassword:      pointer.BoolPtr(false),
+							SSHAuthorizedKeys: []string{"ssh-rsa AAAA tmax@example.com"},
+						},
+					},
+					NTP: &infrav1.NTP{
+						Servers: []string{"0.pool.ntp.org"},
+						Enabled: pointer.BoolPtr(true),
+					},
+				},
+				JoinConfiguration: "apiVersion: kubeadm.k8s.io/v1beta2\nkind: JoinConfiguration\n",
+			},
+		},
+		{
+			name: "hostile",
+			input: &NodeInput{
+				BaseUserData: BaseUserData{
+					CloudInitCommands: []string{"echo '- injected' # comment", "key: value", "echo {% if true %}'{{ v1.instance_id }}'{% endif %}"},
+					AdditionalFiles: []infrav1.File{
+						{
+							Path:        "/tmp/a: b # c",
+							Owner:       "root:root\nruncmd: [reboot]",
+							Permissions: "0600",
+							Content:     "- not a list\n#cloud-config\n",
+						},
+						{
+							Path:        "/etc/motd.tmpl",
+							Permissions: "0644",
+							Content:     "{{ .Name }}\n{% raw %}{{ v1.local_hostname }}{% endraw %}\n",
+						},
+					},
+					Users: []infrav1.User{
+						{
+							Name:    "evil\nsudo: ALL=(ALL) NOPASSWD:ALL",
+							Gecos:   pointer.StringPtr("Evil: User # admin"),
+							Shell:   pointer.StringPtr("/bin/bash\nlock_passwd: false"),
+							Groups:  pointer.StringPtr("wheel, docker: root"),
+							HomeDir: pointer.StringPtr("'/home/evil'"),
+						},
+						{
+							Name:  "jinja",
+							Gecos: pointer.StringPtr("{{ ds.meta_data.hostname }}\nsudo: ALL"),
+						},
+					},
+					NTP: &infrav1.NTP{
+						Servers: []string{"ntp.example.com # - evil", "{ntp: true}", "{{ '\\n' }}evil"},
+					},
+				},
+			},
+		},
+		{
+			name: "storage",
+			input: &NodeInput{
+				BaseUserData: BaseUserData{
+					Disks: []infrav1.Disk{
+						{Device: "/dev/sdb", Layout: []int32{30, 70}},
+						{Device: "/dev/sdc", TableType: infrav1.MBRPartitionTable, Overwrite: pointer.BoolPtr(true)},
+					},
+					VolumeGroups: []infrav1.VolumeGroup{
+						{
+							Name:            "data",
+							PhysicalVolumes: []string{"/dev/sdd", "/dev/sde"},
+							LogicalVolumes: []infrav1.LogicalVolume{
+								{Name: "etcd", Size: "20G"},
+								{Name: "containers", Size: "100%FREE"},
+							},
+						},
+					},
+					Filesystems: []infrav1.Filesystem{
+						{Device: "/dev/sdb", Partition: "1", Filesystem: "xfs", Label: "logs"},
+						{Device: "/dev/sdc1", Filesystem: "ext4", ExtraOpts: []string{"-E", "lazy_itable_init=0"}},
+						{Device: "/dev/data/etcd", Filesystem: "xfs"},
+					},
+					Mounts: []infrav1.Mount{
+						{Device: "LABEL=logs", MountPoint: "/var/log/pods"},
+						{Device: "/dev/data/etcd", MountPoint: "/var/lib/etcd", Type: "xfs", Options: "defaults,noatime"},
+						{Device: "/dev/sdc1", MountPoint: "/mnt/scratch", Options: "nofail"},
+					},
+				},
+			},
+		},
+		{
+			name: "packages",
+			input: &NodeInput{
+				BaseUserData: BaseUserData{
+					Packages: &infrav1.Packages{
+						Update:   true,
+						Packages: []string{"kubelet=1.22.2-00", "kubeadm=1.22.2-00", "chrony"},
+						YumRepos: []infrav1.YumRepo{
+							{
+								ID:      "kubernetes",
+								BaseURL: "https://packages.cloud.google.com/yum/repos/kubernetes-el7-x86_64",
+								GPGKey:  "https://packages.cloud.google.com/yum/doc/rpm-package-key.gpg",
+							},
+							{
+								ID:        "internal",
+								Name:      "Internal mirror",
+								BaseURL:   "http://mirror.example.com/el8/",
+								Enabled:   pointer.BoolPtr(false),
+								GPGKeyRef: &corev1.ConfigMapKeySelector{Key: "internal"},
+							},
+						},
+						AptSources: []infrav1.AptSource{
+							{
+								Name:   "kubernetes",
+								Source: "deb https://apt.kubernetes.io/ kubernetes-xenial main",
+								KeyRef: &corev1.ConfigMapKeySelector{Key: "kubernetes"},
+							},
+							{
+								Name:   "docker",
+								Source: "deb https://download.docker.com/linux/ubuntu $RELEASE stable",
+								KeyID:  "0EBFCD88",
+							},
+						},
+					},
+					PackageKeys: PackageKeys{
+						Yum: map[string]string{"internal": "-----BEGIN PGP PUBLIC KEY BLOCK-----\ninternal\n-----END PGP PUBLIC KEY BLOCK-----\n"},
+						Apt: map[string]string{"kubernetes": "-----BEGIN PGP PUBLIC KEY BLOCK-----\nkubernetes\n-----END PGP PUBLIC KEY BLOCK-----\n"},
+					},
+				},
+			},
+		},
 	}
-	for _, f := range expectedCommands {
-		g.Expect(out).To(ContainSubstring(f))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			out, err := NewNode(tt.input)
+			g.Expect(err).NotTo(HaveOccurred())
+			expectGolden(g, tt.name, out)
+
+			// Every value comes back as it was given
+			config := parse(g, out)
+			for i, c := range tt.input.CloudInitCommands {
+				g.Expect(config.RunCmd[i]).To(Equal(c))
+			}
+			for i, f := range tt.input.AdditionalFiles {
+				g.Expect(config.WriteFiles[i]).To(Equal(convertFile(f)))
+			}
+			for i, u := range tt.input.Users {
+				g.Expect(config.Users[i]).To(Equal(convertUser(u)))
+			}
+			g.Expect(config.NTP).To(Equal(convertNTP(tt.input.NTP)))
+			for i, m := range tt.input.Mounts {
+				g.Expect(config.Mounts[i]).To(Equal(convertMount(m)))
+			}
+		})
 	}
 }
+
+func TestNewNetworkConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	out, err := NewNetworkConfig(&infrav1.Network{
+		Ethernets: []infrav1.Ethernet{
+			{Name: "eno1", MACAddress: "00:5c:52:31:3a:9c"},
+			{Name: "eno2", MACAddress: "00:5c:52:31:3a:9d"},
+		},
+		Bonds: []infrav1.Bond{
+			{
+				Name:               "bond0",
+				Interfaces:         []string{"eno1", "eno2"},
+				Mode:               "802.3ad",
+				MIIMonitorInterval: "100",
+				LACPRate:           "fast",
+				InterfaceConfig: infrav1.InterfaceConfig{
+					MTU: pointer.Int32Ptr(9000),
+				},
+			},
+		},
+		VLANs: []infrav1.VLAN{
+			{
+				Name: "bond0.100",
+				ID:   100,
+				Link: "bond0",
+				InterfaceConfig: infrav1.InterfaceConfig{
+					Addresses: []string{"192.168.100.10/24"},
+					Gateway4:  "192.168.100.1",
+					Nameservers: &infrav1.Nameservers{
+						Addresses: []string{"192.168.100.2"},
+						Search:    []string{"example.com"},
+					},
+					Routes: []infrav1.Route{
+						{To: "10.0.0.0/8", Via: "192.168.100.254", Metric: pointer.Int32Ptr(100)},
+					},
+				},
+			},
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	expectGolden(g, "network", out)
+}
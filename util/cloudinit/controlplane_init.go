@@ -0,0 +1,64 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// KubeadmInitConfigPath is where the kubeadm ClusterConfiguration and
+	// InitConfiguration are written.
+	KubeadmInitConfigPath = "/tmp/kubeadm.yaml"
+
+	// KubeadmInitCommand brings up the first control plane node once the
+	// cloudInitCommands are done.
+	KubeadmInitCommand = "kubeadm init --config " + KubeadmInitConfigPath
+
+	// KubeadmUploadCertsFlag uploads the control plane certificates,
+	// encrypted with the certificate key, for the other control plane nodes.
+	KubeadmUploadCertsFlag = " --upload-certs"
+)
+
+// ControlPlaneInput defines the context to generate a controlplane instance user data.
+type ControlPlaneInput struct {
+	BaseUserData
+
+	ClusterConfiguration string
+	InitConfiguration    string
+	UploadCerts          bool
+}
+
+// NewInitControlPlane returns the user data string to be used on the first
+// controlplane instance.
+func NewInitControlPlane(input *ControlPlaneInput) ([]byte, error) {
+	if input.InitConfiguration == "" || input.ClusterConfiguration == "" {
+		return nil, errors.New("the init and cluster configurations are required for the first controlplane instance")
+	}
+	input.Header = cloudConfigHeader
+	input.WriteFiles = append(input.WriteFiles, input.AdditionalFiles...)
+
+	config := input.cloudConfig()
+	config.WriteFiles = append(config.WriteFiles, kubeadmConfigFile(KubeadmInitConfigPath,
+		"---\n"+input.ClusterConfiguration+"\n---\n"+input.InitConfiguration))
+	command := KubeadmInitCommand
+	if input.UploadCerts {
+		command += KubeadmUploadCertsFlag
+	}
+	config.RunCmd = append(config.RunCmd, command)
+	return generate("InitControlplane", input.Header, config)
+}
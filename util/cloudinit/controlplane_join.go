@@ -0,0 +1,44 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ControlPlaneJoinInput defines context to generate controlplane instance user data for control plane node join.
+type ControlPlaneJoinInput struct {
+	BaseUserData
+
+	JoinConfiguration string
+}
+
+// NewJoinControlPlane returns the user data string to be used on a new
+// controlplane instance joining the cluster.
+func NewJoinControlPlane(input *ControlPlaneJoinInput) ([]byte, error) {
+	if input.JoinConfiguration == "" {
+		return nil, errors.New("the join configuration is required for a joining controlplane instance")
+	}
+	input.Header = cloudConfigHeader
+	input.WriteFiles = append(input.WriteFiles, input.AdditionalFiles...)
+
+	config := input.cloudConfig()
+	config.WriteFiles = append(config.WriteFiles,
+		kubeadmConfigFile(KubeadmJoinConfigPath, "---\n"+input.JoinConfiguration))
+	config.RunCmd = append(config.RunCmd, KubeadmJoinCommand)
+	return generate("JoinControlplane", input.Header, config)
+}
@@ -1,37 +0,0 @@
-/*
-Copyright 2019 The Kubernetes Authors.
-
-Licensed under the Apache License, Version 2.0 (the "License");
-you may not use this file except in compliance with the License.
-You may obtain a copy of the License at
-
-    http://www.apache.org/licenses/LICENSE-2.0
-
-Unless required by applicable law or agreed to in writing, software
-distributed under the License is distributed on an "AS IS" BASIS,
-WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-See the License for the specific language governing permissions and
-limitations under the License.
-*/
-
-package cloudinit
-
-const (
-	filesTemplate = `{{ define "files" -}}
-write_files:{{ range . }}
--   path: {{.Path}}
-    {{ if ne .Encoding "" -}}
-    encoding: "{{.Encoding}}"
-    {{ end -}}
-    {{ if ne .Owner "" -}}
-    owner: {{.Owner}}
-    {{ end -}}
-    {{ if ne .Permissions "" -}}
-    permissions: '{{.Permissions}}'
-    {{ end -}}
-    content: |
-{{.Content | Indent 6}}
-{{- end -}}
-{{- end -}}
-`
-)
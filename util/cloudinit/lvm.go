@@ -0,0 +1,55 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"fmt"
+	"strings"
+
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+)
+
+// lvmCommands returns the bootcmd commands creating the volume groups and
+// their logical volumes. bootcmd runs on every boot, so each command only
+// creates what does not exist yet.
+func lvmCommands(volumeGroups []bootstrapv1.VolumeGroup) []string {
+	var cmds []string
+	for _, vg := range volumeGroups {
+		var pvs []string
+		for _, pv := range vg.PhysicalVolumes {
+			pvs = append(pvs, shellQuote(pv))
+		}
+		name := shellQuote(vg.Name)
+		cmds = append(cmds, fmt.Sprintf("vgs %s >/dev/null 2>&1 || vgcreate %s %s", name, name, strings.Join(pvs, " ")))
+
+		for _, lv := range vg.LogicalVolumes {
+			sizeFlag := "-L"
+			if strings.Contains(lv.Size, "%") {
+				sizeFlag = "-l"
+			}
+			path := shellQuote(vg.Name + "/" + lv.Name)
+			cmds = append(cmds, fmt.Sprintf("lvs %s >/dev/null 2>&1 || lvcreate -y -n %s %s %s %s",
+				path, shellQuote(lv.Name), sizeFlag, shellQuote(lv.Size), name))
+		}
+	}
+	return cmds
+}
+
+// shellQuote quotes s as a single shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
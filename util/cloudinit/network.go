@@ -0,0 +1,167 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	"gopkg.in/yaml.v2"
+)
+
+// networkConfigVersion is the version of the cloud-init network config format.
+const networkConfigVersion = 2
+
+// NetworkConfig is the cloud-init network config version 2, the netplan format.
+type NetworkConfig struct {
+	Version   int                        `yaml:"version"`
+	Ethernets map[string]NetworkEthernet `yaml:"ethernets,omitempty"`
+	Bonds     map[string]NetworkBond     `yaml:"bonds,omitempty"`
+	VLANs     map[string]NetworkVLAN     `yaml:"vlans,omitempty"`
+}
+
+// NetworkInterface is the addressing shared by all the interface kinds.
+type NetworkInterface struct {
+	DHCP4       *bool               `yaml:"dhcp4,omitempty"`
+	DHCP6       *bool               `yaml:"dhcp6,omitempty"`
+	Addresses   []string            `yaml:"addresses,omitempty"`
+	Gateway4    string              `yaml:"gateway4,omitempty"`
+	Gateway6    string              `yaml:"gateway6,omitempty"`
+	Nameservers *NetworkNameservers `yaml:"nameservers,omitempty"`
+	Routes      []NetworkRoute      `yaml:"routes,omitempty"`
+	MTU         *int32              `yaml:"mtu,omitempty"`
+}
+
+// NetworkEthernet is a physical interface, matched by its MAC address.
+type NetworkEthernet struct {
+	Match            NetworkMatch `yaml:"match"`
+	SetName          string       `yaml:"set-name"`
+	NetworkInterface `yaml:",inline"`
+}
+
+// NetworkMatch selects the physical interface.
+type NetworkMatch struct {
+	MACAddress string `yaml:"macaddress"`
+}
+
+// NetworkBond is a bond of ethernets.
+type NetworkBond struct {
+	Interfaces       []string               `yaml:"interfaces"`
+	Parameters       *NetworkBondParameters `yaml:"parameters,omitempty"`
+	NetworkInterface `yaml:",inline"`
+}
+
+// NetworkBondParameters are the bonding options.
+type NetworkBondParameters struct {
+	Mode               string `yaml:"mode,omitempty"`
+	MIIMonitorInterval string `yaml:"mii-monitor-interval,omitempty"`
+	LACPRate           string `yaml:"lacp-rate,omitempty"`
+	TransmitHashPolicy string `yaml:"transmit-hash-policy,omitempty"`
+}
+
+// NetworkVLAN is a VLAN interface.
+type NetworkVLAN struct {
+	ID               int32  `yaml:"id"`
+	Link             string `yaml:"link"`
+	NetworkInterface `yaml:",inline"`
+}
+
+// NetworkNameservers is the DNS configuration of an interface.
+type NetworkNameservers struct {
+	Addresses []string `yaml:"addresses,omitempty"`
+	Search    []string `yaml:"search,omitempty"`
+}
+
+// NetworkRoute is a static route.
+type NetworkRoute struct {
+	To     string `yaml:"to"`
+	Via    string `yaml:"via"`
+	Metric *int32 `yaml:"metric,omitempty"`
+}
+
+// NewNetworkConfig returns the network config to be handed to the host as
+// its network data.
+func NewNetworkConfig(network *bootstrapv1.Network) ([]byte, error) {
+	config := NetworkConfig{Version: networkConfigVersion}
+
+	for _, e := range network.Ethernets {
+		if config.Ethernets == nil {
+			config.Ethernets = map[string]NetworkEthernet{}
+		}
+		config.Ethernets[e.Name] = NetworkEthernet{
+			Match:            NetworkMatch{MACAddress: e.MACAddress},
+			SetName:          e.Name,
+			NetworkInterface: convertInterface(e.InterfaceConfig),
+		}
+	}
+
+	for _, b := range network.Bonds {
+		if config.Bonds == nil {
+			config.Bonds = map[string]NetworkBond{}
+		}
+		bond := NetworkBond{
+			Interfaces:       b.Interfaces,
+			NetworkInterface: convertInterface(b.InterfaceConfig),
+		}
+		if b.Mode != "" || b.MIIMonitorInterval != "" || b.LACPRate != "" || b.TransmitHashPolicy != "" {
+			bond.Parameters = &NetworkBondParameters{
+				Mode:               string(b.Mode),
+				MIIMonitorInterval: b.MIIMonitorInterval,
+				LACPRate:           b.LACPRate,
+				TransmitHashPolicy: b.TransmitHashPolicy,
+			}
+		}
+		config.Bonds[b.Name] = bond
+	}
+
+	for _, v := range network.VLANs {
+		if config.VLANs == nil {
+			config.VLANs = map[string]NetworkVLAN{}
+		}
+		config.VLANs[v.Name] = NetworkVLAN{
+			ID:               v.ID,
+			Link:             v.Link,
+			NetworkInterface: convertInterface(v.InterfaceConfig),
+		}
+	}
+
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal network config")
+	}
+	return out, nil
+}
+
+func convertInterface(i bootstrapv1.InterfaceConfig) NetworkInterface {
+	out := NetworkInterface{
+		DHCP4:     i.DHCP4,
+		DHCP6:     i.DHCP6,
+		Addresses: i.Addresses,
+		Gateway4:  i.Gateway4,
+		Gateway6:  i.Gateway6,
+		MTU:       i.MTU,
+	}
+	if i.Nameservers != nil {
+		out.Nameservers = &NetworkNameservers{
+			Addresses: i.Nameservers.Addresses,
+			Search:    i.Nameservers.Search,
+		}
+	}
+	for _, r := range i.Routes {
+		out.Routes = append(out.Routes, NetworkRoute{To: r.To, Via: r.Via, Metric: r.Metric})
+	}
+	return out
+}
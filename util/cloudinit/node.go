@@ -17,19 +17,12 @@ limitations under the License.
 package cloudinit
 
 const (
-	nodeCloudInit = `{{.Header}}
-{{template "files" .WriteFiles}}
--   path: /tmp/kubeadm-node.yaml
-    owner: root:root
-    permissions: '0640'
-    content: |
-      ---
-{{.JoinConfiguration | Indent 6}}
-runcmd:
-{{- template "commands" .CloudInitCommands }}
-{{- template "ntp" .NTP }}
-{{- template "users" .Users }}
-`
+	// KubeadmJoinConfigPath is where the kubeadm JoinConfiguration is written.
+	KubeadmJoinConfigPath = "/tmp/kubeadm-node.yaml"
+
+	// KubeadmJoinCommand joins the node to the cluster once the
+	// cloudInitCommands are done.
+	KubeadmJoinCommand = "kubeadm join --config " + KubeadmJoinConfigPath
 )
 
 // NodeInput defines the context to generate a node user data.
@@ -43,5 +36,12 @@ type NodeInput struct {
 func NewNode(input *NodeInput) ([]byte, error) {
 	input.Header = cloudConfigHeader
 	input.WriteFiles = append(input.WriteFiles, input.AdditionalFiles...)
-	return generate("Node", nodeCloudInit, input)
+
+	config := input.cloudConfig()
+	if input.JoinConfiguration != "" {
+		config.WriteFiles = append(config.WriteFiles,
+			kubeadmConfigFile(KubeadmJoinConfigPath, "---\n"+input.JoinConfiguration))
+		config.RunCmd = append(config.RunCmd, KubeadmJoinCommand)
+	}
+	return generate("Node", input.Header, config)
 }
@@ -0,0 +1,127 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"path"
+
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// yumGPGKeyDir is where the yum repository keys read from ConfigMaps
+	// are written.
+	yumGPGKeyDir = "/etc/pki/rpm-gpg"
+
+	gpgKeyOwner       = "root:root"
+	gpgKeyPermissions = "0644"
+)
+
+// PackageKeys holds the GPG keys read from the ConfigMaps the package
+// repositories refer to.
+type PackageKeys struct {
+	// Yum holds the keys of the yum repositories by repository id
+	Yum map[string]string
+	// Apt holds the keys of the apt sources by source name
+	Apt map[string]string
+}
+
+// YumRepo is an entry of the yum_repos module. It is written to
+// /etc/yum.repos.d/<id>.repo, the booleans as 1 and 0.
+type YumRepo struct {
+	Name     string `yaml:"name"`
+	BaseURL  string `yaml:"baseurl"`
+	Enabled  bool   `yaml:"enabled"`
+	GPGCheck *bool  `yaml:"gpgcheck,omitempty"`
+	GPGKey   string `yaml:"gpgkey,omitempty"`
+}
+
+// Apt is the apt module. The sources list of the image is kept, the
+// sources are added next to it.
+type Apt struct {
+	PreserveSourcesList bool                 `yaml:"preserve_sources_list"`
+	Sources             map[string]AptSource `yaml:"sources,omitempty"`
+}
+
+// AptSource is an entry of the apt sources, written to
+// /etc/apt/sources.list.d/<name>.list.
+type AptSource struct {
+	Source    string `yaml:"source"`
+	KeyID     string `yaml:"keyid,omitempty"`
+	KeyServer string `yaml:"keyserver,omitempty"`
+	Key       string `yaml:"key,omitempty"`
+}
+
+// yumGPGKeyPath returns where the key of the yum repository read from a
+// ConfigMap is written.
+func yumGPGKeyPath(id string) string {
+	return path.Join(yumGPGKeyDir, "RPM-GPG-KEY-"+id)
+}
+
+// addPackages adds the package repositories and the packages to the cloud
+// config, with the GPG key files of the yum repositories.
+func addPackages(config *CloudConfig, packages *bootstrapv1.Packages, keys PackageKeys) {
+	if packages == nil {
+		return
+	}
+	config.PackageUpdate = packages.Update
+	config.PackageUpgrade = packages.Upgrade
+	config.Packages = packages.Packages
+
+	for _, r := range packages.YumRepos {
+		if config.YumRepos == nil {
+			config.YumRepos = map[string]YumRepo{}
+		}
+		repo := YumRepo{
+			Name:     r.Name,
+			BaseURL:  r.BaseURL,
+			Enabled:  r.Enabled == nil || *r.Enabled,
+			GPGCheck: r.GPGCheck,
+			GPGKey:   r.GPGKey,
+		}
+		if repo.Name == "" {
+			repo.Name = r.ID
+		}
+		if key, ok := keys.Yum[r.ID]; ok {
+			keyPath := yumGPGKeyPath(r.ID)
+			config.WriteFiles = append(config.WriteFiles, File{
+				Path:        keyPath,
+				Owner:       gpgKeyOwner,
+				Permissions: gpgKeyPermissions,
+				Content:     key,
+			})
+			repo.GPGKey = "file://" + keyPath
+		}
+		if repo.GPGCheck == nil && repo.GPGKey != "" {
+			repo.GPGCheck = pointer.BoolPtr(true)
+		}
+		config.YumRepos[r.ID] = repo
+	}
+
+	for _, s := range packages.AptSources {
+		if config.Apt == nil {
+			config.Apt = &Apt{PreserveSourcesList: true, Sources: map[string]AptSource{}}
+		}
+		config.Apt.Sources[s.Name] = AptSource{
+			Source:    s.Source,
+			KeyID:     s.KeyID,
+			KeyServer: s.KeyServer,
+			Key:       keys.Apt[s.Name],
+		}
+	}
+}
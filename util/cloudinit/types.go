@@ -0,0 +1,169 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+)
+
+// CloudConfig is the subset of the cloud-config modules rendered for a node.
+// It is serialized with a YAML encoder, so every value is quoted and escaped
+// as needed.
+type CloudConfig struct {
+	BootCmd    []string             `yaml:"bootcmd,omitempty"`
+	DiskSetup  map[string]DiskSetup `yaml:"disk_setup,omitempty"`
+	FSSetup    []FSSetup            `yaml:"fs_setup,omitempty"`
+	Mounts     [][]string           `yaml:"mounts,omitempty"`
+	WriteFiles []File               `yaml:"write_files,omitempty"`
+	YumRepos   map[string]YumRepo   `yaml:"yum_repos,omitempty"`
+	Apt        *Apt                 `yaml:"apt,omitempty"`
+
+	PackageUpdate  bool     `yaml:"package_update,omitempty"`
+	PackageUpgrade bool     `yaml:"package_upgrade,omitempty"`
+	Packages       []string `yaml:"packages,omitempty"`
+
+	RunCmd []string `yaml:"runcmd,omitempty"`
+	NTP    *NTP     `yaml:"ntp,omitempty"`
+	Users  []User   `yaml:"users,omitempty"`
+}
+
+// File is an entry of the write_files module.
+type File struct {
+	Path        string `yaml:"path"`
+	Encoding    string `yaml:"encoding,omitempty"`
+	Owner       string `yaml:"owner,omitempty"`
+	Permissions string `yaml:"permissions,omitempty"`
+	Content     string `yaml:"content"`
+}
+
+// DiskSetup is an entry of the disk_setup module. Layout is either true, for
+// a single partition spanning the disk, or the partition sizes in percent.
+type DiskSetup struct {
+	TableType string      `yaml:"table_type"`
+	Layout    interface{} `yaml:"layout"`
+	Overwrite *bool       `yaml:"overwrite,omitempty"`
+}
+
+// FSSetup is an entry of the fs_setup module.
+type FSSetup struct {
+	Label      string   `yaml:"label,omitempty"`
+	Filesystem string   `yaml:"filesystem"`
+	Device     string   `yaml:"device"`
+	Partition  string   `yaml:"partition,omitempty"`
+	Overwrite  *bool    `yaml:"overwrite,omitempty"`
+	ExtraOpts  []string `yaml:"extra_opts,omitempty"`
+}
+
+// NTP is the ntp module.
+type NTP struct {
+	Enabled *bool    `yaml:"enabled,omitempty"`
+	Servers []string `yaml:"servers,omitempty"`
+}
+
+// User is an entry of the users module.
+type User struct {
+	Name              string   `yaml:"name"`
+	Passwd            *string  `yaml:"passwd,omitempty"`
+	Gecos             *string  `yaml:"gecos,omitempty"`
+	Groups            *string  `yaml:"groups,omitempty"`
+	HomeDir           *string  `yaml:"homedir,omitempty"`
+	Inactive          *bool    `yaml:"inactive,omitempty"`
+	LockPassword      *bool    `yaml:"lock_passwd,omitempty"`
+	Shell             *string  `yaml:"shell,omitempty"`
+	PrimaryGroup      *string  `yaml:"primary_group,omitempty"`
+	Sudo              *string  `yaml:"sudo,omitempty"`
+	SSHAuthorizedKeys []string `yaml:"ssh_authorized_keys,omitempty"`
+}
+
+func convertFile(f bootstrapv1.File) File {
+	return File{
+		Path:        f.Path,
+		Encoding:    string(f.Encoding),
+		Owner:       f.Owner,
+		Permissions: f.Permissions,
+		Content:     f.Content,
+	}
+}
+
+func convertUser(u bootstrapv1.User) User {
+	return User{
+		Name:              u.Name,
+		Passwd:            u.Passwd,
+		Gecos:             u.Gecos,
+		Groups:            u.Groups,
+		HomeDir:           u.HomeDir,
+		Inactive:          u.Inactive,
+		LockPassword:      u.LockPassword,
+		Shell:             u.Shell,
+		PrimaryGroup:      u.PrimaryGroup,
+		Sudo:              u.Sudo,
+		SSHAuthorizedKeys: u.SSHAuthorizedKeys,
+	}
+}
+
+func convertNTP(ntp *bootstrapv1.NTP) *NTP {
+	if ntp == nil {
+		return nil
+	}
+	return &NTP{
+		Enabled: ntp.Enabled,
+		Servers: ntp.Servers,
+	}
+}
+
+func convertDisk(d bootstrapv1.Disk) DiskSetup {
+	out := DiskSetup{
+		TableType: string(d.TableType),
+		Layout:    true,
+		Overwrite: d.Overwrite,
+	}
+	if out.TableType == "" {
+		out.TableType = string(bootstrapv1.GPTPartitionTable)
+	}
+	if len(d.Layout) > 0 {
+		out.Layout = d.Layout
+	}
+	return out
+}
+
+func convertFilesystem(f bootstrapv1.Filesystem) FSSetup {
+	return FSSetup{
+		Label:      f.Label,
+		Filesystem: f.Filesystem,
+		Device:     f.Device,
+		Partition:  f.Partition,
+		Overwrite:  f.Overwrite,
+		ExtraOpts:  f.ExtraOpts,
+	}
+}
+
+// convertMount returns the mounts entry of the mount. The fields left out
+// are filled with the cloud-init defaults.
+func convertMount(m bootstrapv1.Mount) []string {
+	out := []string{m.Device, m.MountPoint}
+	if m.Type != "" || m.Options != "" {
+		fsType := m.Type
+		if fsType == "" {
+			fsType = "auto"
+		}
+		out = append(out, fsType)
+	}
+	if m.Options != "" {
+		out = append(out, m.Options)
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/url"
+
+	"github.com/pkg/errors"
+	kubeadmv1beta2 "github.com/tmax-cloud/nodeconfig-operator/types/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fillBootstrapTokenDiscovery fills the API server endpoint and the CA cert
+// hashes of the discovery from the cluster-info ConfigMap, when they are left
+// empty.
+func (c *ConfigManager) fillBootstrapTokenDiscovery(ctx context.Context, discovery *kubeadmv1beta2.BootstrapTokenDiscovery) error {
+	needsHashes := len(discovery.CACertHashes) == 0 && !discovery.UnsafeSkipCAVerification
+	if discovery.APIServerEndpoint != "" && !needsHashes {
+		return nil
+	}
+
+	clusterInfo := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: metav1.NamespacePublic, Name: bootstrapapi.ConfigMapClusterInfo}
+	if err := c.client.Get(ctx, key, clusterInfo); err != nil {
+		return errors.Wrapf(err, "failed to get the %s/%s ConfigMap", key.Namespace, key.Name)
+	}
+
+	endpoint, hashes, err := parseClusterInfo([]byte(clusterInfo.Data[bootstrapapi.KubeConfigKey]))
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse the %s/%s ConfigMap", key.Namespace, key.Name)
+	}
+
+	if discovery.APIServerEndpoint == "" {
+		discovery.APIServerEndpoint = endpoint
+	}
+	if needsHashes {
+		discovery.CACertHashes = hashes
+	}
+	return nil
+}
+
+// parseClusterInfo returns the API server endpoint and the CA cert hashes
+// of the kubeconfig published in the cluster-info ConfigMap.
+func parseClusterInfo(kubeconfig []byte) (string, []string, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to load the kubeconfig")
+	}
+	if len(config.Clusters) != 1 {
+		return "", nil, errors.Errorf("expected one cluster in the kubeconfig, got %d", len(config.Clusters))
+	}
+
+	var endpoint string
+	var hashes []string
+	for _, cluster := range config.Clusters {
+		server, err := url.Parse(cluster.Server)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "invalid server URL %q", cluster.Server)
+		}
+		endpoint = server.Host
+
+		if hashes, err = caCertHashes(cluster.CertificateAuthorityData); err != nil {
+			return "", nil, err
+		}
+	}
+	return endpoint, hashes, nil
+}
+
+// caCertHashes returns the "sha256:<hex>" hashes of the Subject Public Key
+// Info of the PEM encoded certificates, as kubeadm pins them.
+func caCertHashes(data []byte) ([]string, error) {
+	var hashes []string
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse the CA certificate")
+		}
+		spkiHash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		hashes = append(hashes, "sha256:"+hex.EncodeToString(spkiHash[:]))
+	}
+	if len(hashes) == 0 {
+		return nil, errors.New("no CA certificate found")
+	}
+	return hashes, nil
+}
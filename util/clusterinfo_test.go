@@ -0,0 +1,56 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"io/ioutil"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseClusterInfo(t *testing.T) {
+	g := NewWithT(t)
+
+	kubeconfig, err := ioutil.ReadFile("testdata/cluster-info.kubeconfig")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	endpoint, hashes, err := parseClusterInfo(kubeconfig)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(endpoint).To(Equal("192.168.0.10:6443"))
+	// openssl x509 -pubkey -in ca.crt -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -hex
+	g.Expect(hashes).To(Equal([]string{"sha256:a20cf80535cc25ab44002b6120cd8cac5d881dd187a1b2a8c8774d0febe54bc3"}))
+}
+
+func TestParseClusterInfoInvalid(t *testing.T) {
+	g := NewWithT(t)
+
+	var tests = []struct {
+		name       string
+		kubeconfig string
+	}{
+		{"not a kubeconfig", "not: [valid"},
+		{"no cluster", "apiVersion: v1\nkind: Config\nclusters: []\n"},
+		{"no CA", "apiVersion: v1\nkind: Config\nclusters:\n- cluster:\n    server: https://192.168.0.10:6443\n  name: \"\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseClusterInfo([]byte(tt.kubeconfig))
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
@@ -18,12 +18,15 @@ package util
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	bmh "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	"github.com/pkg/errors"
 	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
 	"github.com/tmax-cloud/nodeconfig-operator/util/cloudinit"
+	"github.com/tmax-cloud/nodeconfig-operator/util/ignition"
 	corev1 "k8s.io/api/core/v1"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -63,11 +66,8 @@ func (c *ConfigManager) Associate(ctx context.Context, nConfig *bootstrapv1.Node
 	// just... exception handling
 	if c.NodeConfig == nil {
 		// Should have been picked earlier. Do not requeue
-		c.SetError("NodeConfig undefined")
 		return nil
 	}
-	// clear an error if one was previously set
-	c.clearError()
 
 	// ESLEE_TODO: nodeconifg에서 Default OS IMG 설정하게 해줄것인가
 	// config := c.NodeConfig.Spec
@@ -82,17 +82,14 @@ func (c *ConfigManager) Associate(ctx context.Context, nConfig *bootstrapv1.Node
 	bmhost, _ := getHost(ctx, c.NodeConfig, c.client, c.Log)
 	bmhHelper, err := patch.NewHelper(bmhost, c.client)
 	if err != nil {
-		c.SetError("Failed to get the BaremetalHost for the NodeConfig")
-		return err
+		return errors.Wrap(err, "Failed to get the BaremetalHost for the NodeConfig")
 	}
 
 	// Assign node configs(cloud init) to the BMH
 	if err = c.setHostSpec(ctx, bmhost, c.NodeConfig); err != nil {
-		c.SetError(err.Error())
 		return err
 	}
 	if err = bmhHelper.Patch(ctx, bmhost); err != nil {
-		c.SetError(err.Error())
 		return err
 	}
 	c.Log.Info("Success to set host for association!", "BMH.spec", bmhost.Spec)
@@ -116,6 +113,11 @@ func (c *ConfigManager) FindHost(ctx context.Context) (*bmh.BareMetalHost, bool)
 	if host, err := getHost(ctx, c.NodeConfig, c.client, c.Log); err != nil {
 		c.Log.Error(err, "unknown error occurred at finding the BMH")
 	} else if host != nil {
+		// A host already carrying this NodeConfig's user data is ours whatever
+		// its state; the controller follows it from there.
+		if c.isAssociated(host) {
+			return host, true
+		}
 		provState := host.Status.Provisioning.State
 		if (provState == "ready" || provState == "inspecting" ||
 			provState == "registering" || provState == "match profile" ||
@@ -127,14 +129,88 @@ func (c *ConfigManager) FindHost(ctx context.Context) (*bmh.BareMetalHost, bool)
 	return nil, false
 }
 
-// getHost gets the associated host by looking for an annotation on the machine
-// that contains a reference to the host. Returns nil if not found. Assumes the
-// host is in the same namespace as the machine.
+// isAssociated returns true when a previous reconcile has already handed the
+// user data of this NodeConfig to the host.
+func (c *ConfigManager) isAssociated(host *bmh.BareMetalHost) bool {
+	return host.Spec.UserData != nil && host.Spec.UserData.Name == c.NodeConfig.Name
+}
+
+// IsAssociated returns true when the host, if any, already has the user
+// data of this NodeConfig.
+func (c *ConfigManager) IsAssociated(host *bmh.BareMetalHost) bool {
+	return host != nil && c.isAssociated(host)
+}
+
+// ReleaseHost applies the deletion policy of the NodeConfig to its host.
+// It returns true once the host is released and the NodeConfig can go.
+func (c *ConfigManager) ReleaseHost(ctx context.Context) (bool, error) {
+	host, err := getHost(ctx, c.NodeConfig, c.client, c.Log)
+	if err != nil {
+		return false, err
+	}
+	if host == nil {
+		return true, nil
+	}
+
+	switch c.NodeConfig.DeletionPolicy() {
+	case bootstrapv1.DeletionDeprovision:
+		if host.Spec.Image != nil || host.Spec.UserData != nil {
+			c.Log.Info("Deprovisioning the BareMetalHost", "host", host.Name)
+			helper, err := patch.NewHelper(host, c.client)
+			if err != nil {
+				return false, errors.Wrap(err, "Failed to get the BaremetalHost for the NodeConfig")
+			}
+			host.Spec.Image = nil
+			host.Spec.UserData = nil
+			host.Spec.NetworkData = nil
+			host.Spec.MetaData = nil
+			if err := helper.Patch(ctx, host); err != nil {
+				return false, errors.Wrapf(err, "Failed to deprovision the BMH %s/%s", host.Namespace, host.Name)
+			}
+		}
+		switch host.Status.Provisioning.State {
+		case bmh.StateProvisioning, bmh.StateProvisioned, bmh.StateDeprovisioning,
+			bmh.StateExternallyProvisioned:
+			c.Log.Info("Waiting for the BareMetalHost to be deprovisioned",
+				"BMH.provisioning.state", host.Status.Provisioning.State)
+			return false, nil
+		}
+		if err := c.releaseConsumerRef(ctx, host); err != nil {
+			return false, err
+		}
+		return true, nil
+	case bootstrapv1.DeletionDelete:
+		if host.DeletionTimestamp.IsZero() {
+			c.Log.Info("Deleting the BareMetalHost", "host", host.Name)
+			if err := c.client.Delete(ctx, host); err != nil && !apierrors.IsNotFound(err) {
+				return false, errors.Wrapf(err, "Failed to delete the BMH %s/%s", host.Namespace, host.Name)
+			}
+		}
+		c.Log.Info("Waiting for the BareMetalHost to be deleted")
+		return false, nil
+	default:
+		if err := c.releaseConsumerRef(ctx, host); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// getHost gets the host claimed through the host selector, or else the host
+// sharing the name of the NodeConfig. Returns nil if not found, or when a
+// host is yet to be claimed. Assumes the host is in the same namespace as the
+// machine.
 func getHost(ctx context.Context, nConfig *bootstrapv1.NodeConfig,
 	cl client.Client, mLog logr.Logger) (*bmh.BareMetalHost, error) {
 
 	// Set BMH search key
 	hostNamespace, hostName := nConfig.Namespace, nConfig.Name
+	hostRef := nConfig.Status.HostRef
+	if hostRef != nil {
+		hostName = hostRef.Name
+	} else if nConfig.Spec.HostSelector != nil {
+		return nil, nil
+	}
 	host := bmh.BareMetalHost{}
 	key := client.ObjectKey{
 		Name:      hostName,
@@ -146,6 +222,11 @@ func getHost(ctx context.Context, nConfig *bootstrapv1.NodeConfig,
 	} else if err != nil {
 		return nil, err
 	}
+	// A host registered again under the same name is not the claimed one
+	if hostRef != nil && hostRef.UID != host.UID {
+		mLog.Info("The claimed BMH is gone", "host", hostName)
+		return nil, nil
+	}
 	return &host, nil
 }
 
@@ -158,51 +239,162 @@ func (c *ConfigManager) setHostSpec(ctx context.Context, host *bmh.BareMetalHost
 		return nil
 	}
 
-	host.Spec.Image = &bmh.Image{
+	image := &bmh.Image{
 		URL:          c.NodeConfig.Spec.Image.URL,
 		Checksum:     string(c.NodeConfig.Spec.Image.Checksum),
 		ChecksumType: bmh.ChecksumType(c.NodeConfig.ChecksumType()),
 	}
-	host.Spec.UserData = c.NodeConfig.Status.UserData
 
-	// c.Log.Info("set BMH", "BMH.prov.state", host.Status.Provisioning.State)
-	// Start to provisioning only when BMH provisioning state is 'ready'
-	if host.Status.Provisioning.State == "ready" {
+	switch host.Status.Provisioning.State {
+	case bmh.StateProvisioning, bmh.StateDeprovisioning, bmh.StateExternallyProvisioned:
+		// Leave the host alone while the image is being written or removed
+		return nil
+	case bmh.StateProvisioned:
+		// A provisioned host is only changed as the reprovision policy allows.
+		// The BMO deprovisions the host when the image URL changes or the
+		// image is removed, and provisions it again once it is available.
+		policy := c.NodeConfig.ReprovisionPolicy()
+		status := &c.NodeConfig.Status
+		imageChanged := host.Status.Provisioning.Image.URL != image.URL
+		userDataChanged := status.ProvisionedUserDataHash != status.UserDataHash
+		networkDataChanged := status.ProvisionedNetworkDataHash != status.NetworkDataHash
+		metaDataChanged := status.ProvisionedMetaDataHash != status.MetaDataHash
+		switch {
+		case imageChanged && policy != bootstrapv1.ReprovisionNever:
+			c.Log.Info("The image changed. Provision the host again", "image", image.URL)
+			host.Spec.Image = image
+			c.setHostData(host)
+		case (userDataChanged || networkDataChanged || metaDataChanged) && policy == bootstrapv1.ReprovisionAlways:
+			c.Log.Info("The data of the host changed. Deprovision the host to provision it again",
+				"userData", userDataChanged, "networkData", networkDataChanged, "metaData", metaDataChanged)
+			host.Spec.Image = nil
+		}
+		return nil
+	}
+
+	host.Spec.Image = image
+	c.setHostData(host)
+
+	// Power the host on to start provisioning once it has been inspected.
+	// The host watch brings us back here when it gets there.
+	switch host.Status.Provisioning.State {
+	case bmh.StateReady, bmh.StateAvailable:
 		host.Spec.Online = true
 	}
 
 	return nil
 }
 
-// CreateNodeInitConfig creates cloud-init
+// setHostData hands the user data, network data, metadata and hardware
+// settings of the NodeConfig to the host and records the hashes of the data
+// it was handed.
+func (c *ConfigManager) setHostData(host *bmh.BareMetalHost) {
+	status := &c.NodeConfig.Status
+	host.Spec.UserData = status.UserData
+	host.Spec.NetworkData = status.NetworkData
+	host.Spec.MetaData = status.MetaData
+	c.setHostHardware(host)
+	status.ProvisionedUserDataHash = status.UserDataHash
+	status.ProvisionedNetworkDataHash = status.NetworkDataHash
+	status.ProvisionedMetaDataHash = status.MetaDataHash
+}
+
+// CreateNodeInitConfig creates cloud-init or Ignition user data, as the
+// format of the NodeConfig says
 func (c *ConfigManager) CreateNodeInitConfig(ctx context.Context) (string, error) {
-	c.Log.Info("Creating BootstrapData for the node")
+	c.Log.Info("Creating BootstrapData for the node", "format", c.NodeConfig.Format())
 
 	var cloudInitData []byte
 	var cloudinitName string
-	var err error
-	if cloudInitData, err = cloudinit.NewNode(&cloudinit.NodeInput{
-		BaseUserData: cloudinit.BaseUserData{
-			AdditionalFiles:   c.NodeConfig.Spec.Files,
-			NTP:               c.NodeConfig.Spec.NTP,
-			CloudInitCommands: c.NodeConfig.Spec.CloudInitCommands,
-			Users:             c.NodeConfig.Spec.Users,
-		},
-	}); err != nil {
+	kubeadm, err := c.renderKubeadm(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	files, err := c.resolveFiles(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	baseUserData := cloudinit.BaseUserData{
+		AdditionalFiles:   files,
+		NTP:               c.NodeConfig.Spec.NTP,
+		CloudInitCommands: c.NodeConfig.Spec.CloudInitCommands,
+		Users:             c.NodeConfig.Spec.Users,
+		Disks:             c.NodeConfig.Spec.Disks,
+		VolumeGroups:      c.NodeConfig.Spec.VolumeGroups,
+		Filesystems:       c.NodeConfig.Spec.Filesystems,
+		Mounts:            c.NodeConfig.Spec.Mounts,
+		Packages:          c.NodeConfig.Spec.Packages,
+	}
+	if c.NodeConfig.Format() != bootstrapv1.Ignition {
+		if baseUserData.PackageKeys, err = c.packageKeys(ctx); err != nil {
+			return "", err
+		}
+	}
+	switch {
+	case c.NodeConfig.Format() == bootstrapv1.Ignition:
+		cloudInitData, err = ignition.NewNode(&ignition.NodeInput{
+			AdditionalFiles:      files,
+			NTP:                  c.NodeConfig.Spec.NTP,
+			CloudInitCommands:    c.NodeConfig.Spec.CloudInitCommands,
+			Users:                c.NodeConfig.Spec.Users,
+			ClusterConfiguration: kubeadm.ClusterConfiguration,
+			InitConfiguration:    kubeadm.InitConfiguration,
+			UploadCerts:          kubeadm.UploadCerts,
+			JoinConfiguration:    kubeadm.JoinConfiguration,
+		})
+	case c.NodeConfig.Role() == bootstrapv1.ControlPlaneInitRole:
+		cloudInitData, err = cloudinit.NewInitControlPlane(&cloudinit.ControlPlaneInput{
+			BaseUserData:         baseUserData,
+			ClusterConfiguration: kubeadm.ClusterConfiguration,
+			InitConfiguration:    kubeadm.InitConfiguration,
+			UploadCerts:          kubeadm.UploadCerts,
+		})
+	case c.NodeConfig.Role() == bootstrapv1.ControlPlaneJoinRole:
+		cloudInitData, err = cloudinit.NewJoinControlPlane(&cloudinit.ControlPlaneJoinInput{
+			BaseUserData:      baseUserData,
+			JoinConfiguration: kubeadm.JoinConfiguration,
+		})
+	default:
+		cloudInitData, err = cloudinit.NewNode(&cloudinit.NodeInput{
+			BaseUserData:      baseUserData,
+			JoinConfiguration: kubeadm.JoinConfiguration,
+		})
+	}
+	if err != nil {
 		c.Log.Error(err, "failed to create node configuration")
 		return "", err
 	}
 
-	if cloudinitName, err = c.storeBootstrapData(ctx, cloudInitData); err != nil {
-		if apierrors.IsAlreadyExists(err) {
-			c.Log.Info("cloudinit secret " + c.NodeConfig.Namespace + "/" + c.NodeConfig.Name + "is already created")
+	// Nothing to store if the rendered user data did not change and the
+	// secret holding it is still there
+	hash := dataHash(cloudInitData)
+	if c.NodeConfig.Status.UserData != nil && c.NodeConfig.Status.UserDataHash == hash {
+		secret := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: c.NodeConfig.Namespace, Name: c.NodeConfig.Status.UserData.Name}
+		err := c.client.Get(ctx, key, secret)
+		if err == nil {
+			return c.NodeConfig.Status.UserData.Name, nil
+		} else if !apierrors.IsNotFound(err) {
+			return "", errors.Wrapf(err, "failed to get bootstrap data secret for NodeConfig %s/%s", c.NodeConfig.Namespace, c.NodeConfig.Name)
 		}
+		c.Log.Info("The bootstrap data secret is gone. Store it again", "secret", key.Name)
+	}
+
+	if cloudinitName, err = c.storeBootstrapData(ctx, cloudInitData, kubeadm.token); err != nil {
 		c.Log.Error(err, "failed to store bootstrap data")
 		return "", err
 	}
+	c.NodeConfig.Status.UserDataHash = hash
 	return cloudinitName, nil
 }
 
+// dataHash returns the hex encoded sha256 hash of the data.
+func dataHash(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
 // CreateBareMetalHost creates BMH if there is not
 func (c *ConfigManager) CreateBareMetalHost(ctx context.Context) error {
 	c.Log.Info("Creating BareMetalHost for the node")
@@ -223,15 +415,19 @@ func (c *ConfigManager) CreateBareMetalHost(ctx context.Context) error {
 	bmhost.Spec.Online = false
 	bmhost.Spec.BootMode = bmh.BootMode(c.NodeConfig.BootMode())
 	bmhost.Spec.BMC.Address = c.NodeConfig.Spec.BMC.Address
-	bmhost.Spec.BMC.CredentialsName = c.NodeConfig.Name + "-bmc-secret"
+	bmhost.Spec.BMC.CredentialsName = c.NodeConfig.BMCCredentialsName()
 	bmhost.Spec.BMC.DisableCertificateVerification = true
+	c.setHostHardware(bmhost)
 
 	var secret *corev1.Secret
 	var err error
-	// Create BMH-credential (BMC info)
-	if secret, err = c.storeBMHCredentials(ctx, bmhost); err != nil {
-		c.Log.Error(err, "failed to store BMC credentials")
-		return err
+	// Create BMH-credential (BMC info) from the deprecated inline credentials.
+	// A referenced secret is handed to the BMH as is.
+	if c.NodeConfig.Spec.BMC.CredentialsSecretRef == nil {
+		if secret, err = c.storeBMHCredentials(ctx, bmhost); err != nil {
+			c.Log.Error(err, "failed to store BMC credentials")
+			return err
+		}
 	}
 
 	// Create BMH
@@ -241,9 +437,11 @@ func (c *ConfigManager) CreateBareMetalHost(ctx context.Context) error {
 	}
 
 	// Set owner reference (the BMH owns BMC-credential)
-	if err = c.setBMHCredentialsOwner(ctx, bmhost, secret); err != nil {
-		c.Log.Info("failed to set BMC-credential owner")
-		return err
+	if secret != nil {
+		if err = c.setBMHCredentialsOwner(ctx, bmhost, secret); err != nil {
+			c.Log.Info("failed to set BMC-credential owner")
+			return err
+		}
 	}
 
 	c.Log.Info("Success to create BMH", "BMH.spec", bmhost.Spec, "BMH.status", bmhost.Status)
@@ -251,8 +449,9 @@ func (c *ConfigManager) CreateBareMetalHost(ctx context.Context) error {
 }
 
 // storeBootstrapData creates a new secret with the data passed in as input,
-// sets the reference in the configuration status and ready to true.
-func (c *ConfigManager) storeBootstrapData(ctx context.Context, data []byte) (string, error) {
+// or updates the existing one in place. The bootstrap token rendered into the
+// data, if any, is kept next to it.
+func (c *ConfigManager) storeBootstrapData(ctx context.Context, data []byte, token string) (string, error) {
 	c.Log.Info("Store the Bootstrap data", "secret", c.NodeConfig.Status.DataSecretName)
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -272,9 +471,23 @@ func (c *ConfigManager) storeBootstrapData(ctx context.Context, data []byte) (st
 			"value": data,
 		},
 	}
+	if token != "" {
+		secret.Data[bootstrapTokenKey] = []byte(token)
+	}
 
-	if err := c.client.Create(ctx, secret); err != nil {
-		return "", errors.Wrapf(err, "failed to create bootstrap data secret for NodeConfig %s/%s", c.NodeConfig.Namespace, c.NodeConfig.Name)
+	err := c.client.Create(ctx, secret)
+	if apierrors.IsAlreadyExists(err) {
+		// The user data changed since the secret was created. Update it in place.
+		c.Log.Info("cloudinit secret " + c.NodeConfig.Namespace + "/" + c.NodeConfig.Name + " is already created. Update it")
+		existing := &corev1.Secret{}
+		if err = c.client.Get(ctx, client.ObjectKeyFromObject(secret), existing); err != nil {
+			return "", errors.Wrapf(err, "failed to get bootstrap data secret for NodeConfig %s/%s", c.NodeConfig.Namespace, c.NodeConfig.Name)
+		}
+		existing.Data = secret.Data
+		err = c.client.Update(ctx, existing)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to store bootstrap data secret for NodeConfig %s/%s", c.NodeConfig.Namespace, c.NodeConfig.Name)
 	}
 
 	return secret.Name, nil
@@ -302,10 +515,10 @@ func (c *ConfigManager) setBMHCredentialsOwner(ctx context.Context, bmhost *bmh.
 
 // storeBMHCredentials creates a new secret with the BMH data passed in as input
 func (c *ConfigManager) storeBMHCredentials(ctx context.Context, bmhost *bmh.BareMetalHost) (*corev1.Secret, error) {
-	c.Log.Info("Store the BMC secret", "BMC", c.NodeConfig.Spec.BMC)
+	c.Log.Info("Store the BMC secret", "secret", bmhost.Spec.BMC.CredentialsName)
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      c.NodeConfig.Name + "-bmc-secret",
+			Name:      bmhost.Spec.BMC.CredentialsName,
 			Namespace: c.NodeConfig.Namespace,
 		},
 		Type: "Opaque",
@@ -315,24 +528,24 @@ func (c *ConfigManager) storeBMHCredentials(ctx context.Context, bmhost *bmh.Bar
 		},
 	}
 
-	if err := c.client.Create(ctx, secret); err != nil {
+	err := c.client.Create(ctx, secret)
+	if apierrors.IsAlreadyExists(err) {
+		// The secret of a host deleted to be registered again may still be
+		// waiting for garbage collection. Take it over for the new host.
+		existing := &corev1.Secret{}
+		if err = c.client.Get(ctx, client.ObjectKeyFromObject(secret), existing); err != nil {
+			return nil, errors.Wrapf(err, "failed to get BMC secret for BareMetalHost %s/%s", c.NodeConfig.Namespace, c.NodeConfig.Name)
+		}
+		if !existing.DeletionTimestamp.IsZero() {
+			return nil, errors.Errorf("BMC secret %s/%s is still being deleted", existing.Namespace, existing.Name)
+		}
+		existing.OwnerReferences = nil
+		existing.Data = secret.Data
+		secret = existing
+		err = c.client.Update(ctx, secret)
+	}
+	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create BMC secret for BareMetalHost %s/%s", c.NodeConfig.Namespace, c.NodeConfig.Name)
 	}
 	return secret, nil
 }
-
-// SetError sets the ErrorMessage and ErrorReason fields on the machine and logs
-// the message. It assumes the reason is invalid configuration, since that is
-// currently the only relevant MachineStatusError choice.
-func (c *ConfigManager) SetError(message string) {
-	c.NodeConfig.Status.FailureMessage = &message
-}
-
-// clearError removes the ErrorMessage from the machine's Status if set. Returns
-// nil if ErrorMessage was already nil. Returns a RequeueAfterError if the
-// machine was updated.
-func (c *ConfigManager) clearError() {
-	if c.NodeConfig.Status.FailureMessage != nil {
-		c.NodeConfig.Status.FailureMessage = nil
-	}
-}
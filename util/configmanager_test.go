@@ -0,0 +1,345 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	bmh "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	. "github.com/onsi/gomega"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestSetHostSpecProvisioned(t *testing.T) {
+	const imageURL = "http://192.168.111.1:6180/images/node.qcow2"
+
+	var tests = []struct {
+		name            string
+		policy          bootstrapv1.ReprovisionPolicy
+		imageURL        string
+		status          bootstrapv1.NodeConfigStatus
+		wantDeprovision bool
+	}{
+		{
+			name:   "nothing changed",
+			policy: bootstrapv1.ReprovisionAlways,
+		},
+		{
+			name:            "user data changed",
+			policy:          bootstrapv1.ReprovisionAlways,
+			status:          bootstrapv1.NodeConfigStatus{UserDataHash: "new"},
+			wantDeprovision: true,
+		},
+		{
+			name:            "network data changed",
+			policy:          bootstrapv1.ReprovisionAlways,
+			status:          bootstrapv1.NodeConfigStatus{NetworkDataHash: "new"},
+			wantDeprovision: true,
+		},
+		{
+			name:            "metadata changed",
+			policy:          bootstrapv1.ReprovisionAlways,
+			status:          bootstrapv1.NodeConfigStatus{MetaDataHash: "new"},
+			wantDeprovision: true,
+		},
+		{
+			name:   "network data changed without reprovisioning",
+			policy: bootstrapv1.ReprovisionOnImageChange,
+			status: bootstrapv1.NodeConfigStatus{NetworkDataHash: "new"},
+		},
+		{
+			name:   "user data changed without reprovisioning",
+			policy: bootstrapv1.ReprovisionNever,
+			status: bootstrapv1.NodeConfigStatus{UserDataHash: "new"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config := &bootstrapv1.NodeConfig{
+				Spec: bootstrapv1.NodeConfigSpec{
+					Image:             &bootstrapv1.Image{URL: imageURL},
+					ReprovisionPolicy: tt.policy,
+				},
+				Status: tt.status,
+			}
+			config.Status.UserData = &corev1.SecretReference{Name: "node"}
+			host := &bmh.BareMetalHost{
+				Spec: bmh.BareMetalHostSpec{Image: &bmh.Image{URL: imageURL}},
+				Status: bmh.BareMetalHostStatus{Provisioning: bmh.ProvisionStatus{
+					State: bmh.StateProvisioned,
+					Image: bmh.Image{URL: imageURL},
+				}},
+			}
+			c := &ConfigManager{NodeConfig: config, Log: log.Log}
+			g.Expect(c.setHostSpec(context.Background(), host, config)).To(Succeed())
+			if tt.wantDeprovision {
+				g.Expect(host.Spec.Image).To(BeNil())
+			} else {
+				g.Expect(host.Spec.Image).NotTo(BeNil())
+			}
+		})
+	}
+}
+
+func TestSetHostSpecImageChanged(t *testing.T) {
+	g := NewWithT(t)
+
+	config := &bootstrapv1.NodeConfig{
+		Spec: bootstrapv1.NodeConfigSpec{
+			Image:             &bootstrapv1.Image{URL: "http://192.168.111.1:6180/images/node-v2.qcow2"},
+			ReprovisionPolicy: bootstrapv1.ReprovisionOnImageChange,
+		},
+		Status: bootstrapv1.NodeConfigStatus{
+			UserData:        &corev1.SecretReference{Name: "node"},
+			NetworkData:     &corev1.SecretReference{Name: "node-networkdata"},
+			MetaData:        &corev1.SecretReference{Name: "node-metadata"},
+			UserDataHash:    "user",
+			NetworkDataHash: "network",
+			MetaDataHash:    "meta",
+		},
+	}
+	host := &bmh.BareMetalHost{Status: bmh.BareMetalHostStatus{Provisioning: bmh.ProvisionStatus{
+		State: bmh.StateProvisioned,
+		Image: bmh.Image{URL: "http://192.168.111.1:6180/images/node.qcow2"},
+	}}}
+	c := &ConfigManager{NodeConfig: config, Log: log.Log}
+	g.Expect(c.setHostSpec(context.Background(), host, config)).To(Succeed())
+	g.Expect(host.Spec.Image.URL).To(Equal(config.Spec.Image.URL))
+	g.Expect(host.Spec.NetworkData).To(Equal(config.Status.NetworkData))
+	g.Expect(host.Spec.MetaData).To(Equal(config.Status.MetaData))
+
+	// The data handed along with the new image is recorded
+	g.Expect(config.Status.ProvisionedUserDataHash).To(Equal("user"))
+	g.Expect(config.Status.ProvisionedNetworkDataHash).To(Equal("network"))
+	g.Expect(config.Status.ProvisionedMetaDataHash).To(Equal("meta"))
+}
+
+func TestStoreBMHCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	NewWithT(t).Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	config := &bootstrapv1.NodeConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default"},
+		Spec:       bootstrapv1.NodeConfigSpec{BMC: &bootstrapv1.BMC{Username: "admin", Password: "new"}},
+	}
+	host := &bmh.BareMetalHost{Spec: bmh.BareMetalHostSpec{BMC: bmh.BMCDetails{CredentialsName: "node-bmc-secret"}}}
+	// oldSecret is the secret of the deleted host, still waiting for
+	// garbage collection
+	oldSecret := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "node-bmc-secret",
+				Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: bmh.GroupVersion.String(), Kind: "BareMetalHost", Name: "node", UID: "old",
+				}},
+			},
+			Data: map[string][]byte{"username": []byte("admin"), "password": []byte("old")},
+		}
+	}
+
+	t.Run("creates the secret", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+		c := &ConfigManager{client: cl, NodeConfig: config, Log: log.Log}
+		_, err := c.storeBMHCredentials(ctx, host)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		secret := &corev1.Secret{}
+		g.Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "node-bmc-secret"}, secret)).To(Succeed())
+		g.Expect(secret.Data["password"]).To(BeEquivalentTo("new"))
+	})
+
+	t.Run("takes over the secret of a deleted host", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(oldSecret()).Build()
+		c := &ConfigManager{client: cl, NodeConfig: config, Log: log.Log}
+		_, err := c.storeBMHCredentials(ctx, host)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		secret := &corev1.Secret{}
+		g.Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "node-bmc-secret"}, secret)).To(Succeed())
+		g.Expect(secret.Data["password"]).To(BeEquivalentTo("new"))
+		g.Expect(secret.OwnerReferences).To(BeEmpty())
+	})
+
+	t.Run("waits for a secret being deleted", func(t *testing.T) {
+		g := NewWithT(t)
+
+		deleting := oldSecret()
+		deleting.Finalizers = []string{"test"}
+		now := metav1.Now()
+		deleting.DeletionTimestamp = &now
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(deleting).Build()
+		c := &ConfigManager{client: cl, NodeConfig: config, Log: log.Log}
+		_, err := c.storeBMHCredentials(ctx, host)
+		g.Expect(err).To(MatchError(ContainSubstring("still being deleted")))
+	})
+}
+
+func TestCreateNodeInitConfigStoresMissingSecret(t *testing.T) {
+	ctx := context.Background()
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	config := &bootstrapv1.NodeConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default", UID: "uid-node"},
+		Spec:       bootstrapv1.NodeConfigSpec{CloudInitCommands: []string{"echo hello"}},
+	}
+	c := &ConfigManager{client: cl, NodeConfig: config, Log: log.Log}
+	key := client.ObjectKey{Namespace: "default", Name: "node"}
+
+	name, err := c.CreateNodeInitConfig(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(name).To(Equal("node"))
+	config.Status.UserData = &corev1.SecretReference{Name: name, Namespace: "default"}
+	secret := &corev1.Secret{}
+	g.Expect(cl.Get(ctx, key, secret)).To(Succeed())
+	data := secret.Data["value"]
+
+	// The user data secret is stored again when it is gone, even though the
+	// rendered user data did not change
+	g.Expect(cl.Delete(ctx, secret)).To(Succeed())
+	_, err = c.CreateNodeInitConfig(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	secret = &corev1.Secret{}
+	g.Expect(cl.Get(ctx, key, secret)).To(Succeed())
+	g.Expect(secret.Data["value"]).To(Equal(data))
+}
+
+func TestReleaseHost(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	NewWithT(t).Expect(bmh.AddToScheme(scheme)).To(Succeed())
+	image := &bmh.Image{URL: "http://192.168.111.1:6180/images/node.qcow2"}
+	userData := &corev1.SecretReference{Name: "node", Namespace: "default"}
+
+	var tests = []struct {
+		name         string
+		policy       bootstrapv1.DeletionPolicy
+		state        bmh.ProvisioningState
+		claimed      bool
+		wantReleased bool
+		wantHost     bool
+		wantImage    bool
+	}{
+		{
+			name:         "detach leaves the host",
+			policy:       bootstrapv1.DeletionDetach,
+			state:        bmh.StateProvisioned,
+			wantReleased: true,
+			wantHost:     true,
+			wantImage:    true,
+		},
+		{
+			name:         "detach gives a claimed host back",
+			state:        bmh.StateProvisioned,
+			claimed:      true,
+			wantReleased: true,
+			wantHost:     true,
+			wantImage:    true,
+		},
+		{
+			name:     "deprovision waits for the host",
+			policy:   bootstrapv1.DeletionDeprovision,
+			state:    bmh.StateProvisioned,
+			claimed:  true,
+			wantHost: true,
+		},
+		{
+			name:         "deprovision releases a deprovisioned host",
+			policy:       bootstrapv1.DeletionDeprovision,
+			state:        bmh.StateAvailable,
+			claimed:      true,
+			wantReleased: true,
+			wantHost:     true,
+		},
+		{
+			name:   "delete deletes the host",
+			policy: bootstrapv1.DeletionDelete,
+			state:  bmh.StateProvisioned,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config := &bootstrapv1.NodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default", UID: "uid-node"},
+				Spec:       bootstrapv1.NodeConfigSpec{DeletionPolicy: tt.policy},
+			}
+			host := newTestHost("node", nil, tt.state)
+			host.Spec.Image = image
+			host.Spec.UserData = userData
+			c := &ConfigManager{NodeConfig: config, Log: log.Log}
+			if tt.claimed {
+				host.Name = "host-0"
+				host.Spec.ConsumerRef = c.consumerRef()
+				config.Spec.HostSelector = &bootstrapv1.HostSelector{}
+				config.Status.HostRef = &corev1.ObjectReference{Name: host.Name, Namespace: host.Namespace, UID: host.UID}
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(host).Build()
+			c.client = cl
+
+			released, err := c.ReleaseHost(ctx)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(released).To(Equal(tt.wantReleased))
+
+			got := &bmh.BareMetalHost{}
+			err = cl.Get(ctx, client.ObjectKeyFromObject(host), got)
+			if !tt.wantHost {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				// The NodeConfig is released once the host is gone
+				released, err = c.ReleaseHost(ctx)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(released).To(BeTrue())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			if tt.wantImage {
+				g.Expect(got.Spec.Image).To(Equal(image))
+				g.Expect(got.Spec.UserData).To(Equal(userData))
+			} else {
+				g.Expect(got.Spec.Image).To(BeNil())
+				g.Expect(got.Spec.UserData).To(BeNil())
+			}
+			// A claimed host is given back once it is released
+			if tt.claimed && tt.wantReleased {
+				g.Expect(got.Spec.ConsumerRef).To(BeNil())
+			} else if tt.claimed {
+				g.Expect(got.Spec.ConsumerRef).NotTo(BeNil())
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/base64"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resolveFiles returns the files of the NodeConfig with the content of the
+// ones reading it from a Secret or a ConfigMap filled in. A content that is
+// not valid UTF-8 and has no encoding is base64 encoded, so binary data gets
+// through the user data unchanged.
+func (c *ConfigManager) resolveFiles(ctx context.Context) ([]bootstrapv1.File, error) {
+	var files []bootstrapv1.File
+	for _, f := range c.NodeConfig.Spec.Files {
+		if f.ContentFrom == nil {
+			files = append(files, f)
+			continue
+		}
+
+		data, err := c.fileContent(ctx, f.ContentFrom)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the content of file %s", f.Path)
+		}
+		f.ContentFrom = nil
+		f.Content = string(data)
+		if f.Encoding == "" && !utf8.Valid(data) {
+			f.Encoding = bootstrapv1.Base64
+			f.Content = base64.StdEncoding.EncodeToString(data)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// fileContent reads the key the file source selects. A missing Secret,
+// ConfigMap or key is an error.
+func (c *ConfigManager) fileContent(ctx context.Context, source *bootstrapv1.FileSource) ([]byte, error) {
+	switch {
+	case source.Secret != nil:
+		key := client.ObjectKey{Namespace: c.NodeConfig.Namespace, Name: source.Secret.Name}
+		secret := &corev1.Secret{}
+		if err := c.client.Get(ctx, key, secret); err != nil {
+			return nil, errors.Wrapf(err, "failed to get Secret %s/%s", key.Namespace, key.Name)
+		}
+		data, ok := secret.Data[source.Secret.Key]
+		if !ok {
+			return nil, errors.Errorf("Secret %s/%s has no key %s", key.Namespace, key.Name, source.Secret.Key)
+		}
+		return data, nil
+	case source.ConfigMap != nil:
+		value, _, err := c.configMapKey(ctx, &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: source.ConfigMap.Name},
+			Key:                  source.ConfigMap.Key,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []byte(value), nil
+	}
+	return nil, errors.New("neither secret nor configMap set in contentFrom")
+}
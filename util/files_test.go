@@ -0,0 +1,131 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestResolveFiles(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	NewWithT(t).Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pki", Namespace: "default"},
+			Data:       map[string][]byte{"tls.key": []byte("private key"), "blob": {0xff, 0x00, 0xfe}},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubelet", Namespace: "default"},
+			Data:       map[string]string{"config.yaml": "kind: KubeletConfiguration"},
+			BinaryData: map[string][]byte{"bin": {0xca, 0xfe}},
+		},
+	).Build()
+
+	var tests = []struct {
+		name    string
+		file    bootstrapv1.File
+		want    bootstrapv1.File
+		wantErr string
+	}{
+		{
+			name: "inline content",
+			file: bootstrapv1.File{Path: "/etc/motd", Content: "hi"},
+			want: bootstrapv1.File{Path: "/etc/motd", Content: "hi"},
+		},
+		{
+			name: "secret",
+			file: bootstrapv1.File{
+				Path:        "/etc/pki/tls.key",
+				Permissions: "0600",
+				ContentFrom: &bootstrapv1.FileSource{Secret: &bootstrapv1.FileSourceKey{Name: "pki", Key: "tls.key"}},
+			},
+			want: bootstrapv1.File{Path: "/etc/pki/tls.key", Permissions: "0600", Content: "private key"},
+		},
+		{
+			name: "binary secret data is base64 encoded",
+			file: bootstrapv1.File{
+				Path:        "/opt/blob",
+				ContentFrom: &bootstrapv1.FileSource{Secret: &bootstrapv1.FileSourceKey{Name: "pki", Key: "blob"}},
+			},
+			want: bootstrapv1.File{Path: "/opt/blob", Encoding: bootstrapv1.Base64, Content: "/wD+"},
+		},
+		{
+			name: "configmap data",
+			file: bootstrapv1.File{
+				Path:        "/var/lib/kubelet/config.yaml",
+				ContentFrom: &bootstrapv1.FileSource{ConfigMap: &bootstrapv1.FileSourceKey{Name: "kubelet", Key: "config.yaml"}},
+			},
+			want: bootstrapv1.File{Path: "/var/lib/kubelet/config.yaml", Content: "kind: KubeletConfiguration"},
+		},
+		{
+			name: "configmap binary data",
+			file: bootstrapv1.File{
+				Path:        "/opt/bin",
+				ContentFrom: &bootstrapv1.FileSource{ConfigMap: &bootstrapv1.FileSourceKey{Name: "kubelet", Key: "bin"}},
+			},
+			want: bootstrapv1.File{Path: "/opt/bin", Encoding: bootstrapv1.Base64, Content: "yv4="},
+		},
+		{
+			name: "missing key",
+			file: bootstrapv1.File{
+				Path:        "/etc/pki/tls.crt",
+				ContentFrom: &bootstrapv1.FileSource{Secret: &bootstrapv1.FileSourceKey{Name: "pki", Key: "tls.crt"}},
+			},
+			wantErr: "Secret default/pki has no key tls.crt",
+		},
+		{
+			name: "missing configmap",
+			file: bootstrapv1.File{
+				Path:        "/etc/motd",
+				ContentFrom: &bootstrapv1.FileSource{ConfigMap: &bootstrapv1.FileSourceKey{Name: "motd", Key: "motd"}},
+			},
+			wantErr: "failed to get ConfigMap default/motd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := &ConfigManager{
+				client: cl,
+				NodeConfig: &bootstrapv1.NodeConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default"},
+					Spec:       bootstrapv1.NodeConfigSpec{Files: []bootstrapv1.File{tt.file}},
+				},
+				Log: log.Log,
+			}
+			files, err := c.resolveFiles(ctx)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(files).To(Equal([]bootstrapv1.File{tt.want}))
+		})
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sort"
+	"strings"
+
+	bmh "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+)
+
+// setHostHardware sets the root device hints and the RAID volumes of the
+// NodeConfig on the host.
+func (c *ConfigManager) setHostHardware(host *bmh.BareMetalHost) {
+	host.Spec.RootDeviceHints = nil
+	if storage := c.NodeConfig.Spec.Storage; storage != nil {
+		host.Spec.RootDeviceHints = convertRootDeviceHints(storage.RootDeviceHints)
+	}
+	host.Spec.RAID = convertRAID(c.NodeConfig.Spec.RAID)
+}
+
+func convertRootDeviceHints(hints *bootstrapv1.RootDeviceHints) *bmh.RootDeviceHints {
+	if hints == nil {
+		return nil
+	}
+	return &bmh.RootDeviceHints{
+		DeviceName:         hints.DeviceName,
+		HCTL:               hints.HCTL,
+		Model:              hints.Model,
+		Vendor:             hints.Vendor,
+		SerialNumber:       hints.SerialNumber,
+		MinSizeGigabytes:   hints.MinSizeGigabytes,
+		WWN:                hints.WWN,
+		WWNWithExtension:   hints.WWNWithExtension,
+		WWNVendorExtension: hints.WWNVendorExtension,
+		Rotational:         hints.Rotational,
+	}
+}
+
+func convertRAID(raid *bootstrapv1.RAID) *bmh.RAIDConfig {
+	if raid == nil {
+		return nil
+	}
+	out := &bmh.RAIDConfig{}
+	for _, v := range raid.HardwareRAIDVolumes {
+		out.HardwareRAIDVolumes = append(out.HardwareRAIDVolumes, bmh.HardwareRAIDVolume{
+			Name:                  v.Name,
+			Level:                 v.Level,
+			SizeGibibytes:         v.SizeGibibytes,
+			Rotational:            v.Rotational,
+			NumberOfPhysicalDisks: v.NumberOfPhysicalDisks,
+		})
+	}
+	for _, v := range raid.SoftwareRAIDVolumes {
+		volume := bmh.SoftwareRAIDVolume{
+			Level:         v.Level,
+			SizeGibibytes: v.SizeGibibytes,
+		}
+		for i := range v.PhysicalDisks {
+			volume.PhysicalDisks = append(volume.PhysicalDisks, *convertRootDeviceHints(&v.PhysicalDisks[i]))
+		}
+		out.SoftwareRAIDVolumes = append(out.SoftwareRAIDVolumes, volume)
+	}
+	return out
+}
+
+// CheckHardwareRequirements checks the hardware requirements against the
+// hardware details of the host. It returns false while the host has not
+// been inspected, and an error describing the first requirement the host
+// does not meet.
+func CheckHardwareRequirements(requirements *bootstrapv1.HardwareRequirements, host *bmh.BareMetalHost) (bool, error) {
+	if host == nil || host.Status.HardwareDetails == nil {
+		return false, nil
+	}
+	details := host.Status.HardwareDetails
+
+	if details.CPU.Count < requirements.MinCPUs {
+		return true, errors.Errorf("the host has %d CPUs, less than the %d required", details.CPU.Count, requirements.MinCPUs)
+	}
+	if details.RAMMebibytes < requirements.MinRAMMebibytes {
+		return true, errors.Errorf("the host has %d MiB of RAM, less than the %d MiB required",
+			details.RAMMebibytes, requirements.MinRAMMebibytes)
+	}
+
+	minNICs := requirements.MinNICs
+	if minNICs == 0 && requirements.MinNICSpeedGbps > 0 {
+		minNICs = 1
+	}
+	nics := 0
+	for _, nic := range details.NIC {
+		if nic.SpeedGbps >= requirements.MinNICSpeedGbps {
+			nics++
+		}
+	}
+	if nics < minNICs {
+		return true, errors.Errorf("the host has %d NICs of at least %d Gbps, less than the %d required",
+			nics, requirements.MinNICSpeedGbps, minNICs)
+	}
+
+	if i, ok := matchDisks(requirements.Disks, details.Storage); !ok {
+		disk := requirements.Disks[i]
+		diskType := string(disk.Type)
+		if diskType == "" {
+			diskType = "any type"
+		}
+		return true, errors.Errorf("the host has no disk left for disk requirement %d, %d GiB of %s", i, disk.MinSizeGigabytes, diskType)
+	}
+	return true, nil
+}
+
+// matchDisks meets each disk requirement with a different disk. The typed
+// requirements go first, the largest first, and each one takes the smallest
+// disk meeting it. It returns the index of a requirement no disk is left
+// for.
+func matchDisks(requirements []bootstrapv1.DiskRequirement, disks []bmh.Storage) (int, bool) {
+	order := make([]int, len(requirements))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := requirements[order[i]], requirements[order[j]]
+		if (a.Type != "") != (b.Type != "") {
+			return a.Type != ""
+		}
+		return a.MinSizeGigabytes > b.MinSizeGigabytes
+	})
+
+	used := make([]bool, len(disks))
+	for _, i := range order {
+		minSize := bmh.Capacity(requirements[i].MinSizeGigabytes) * bmh.GibiByte
+		found := -1
+		for j, disk := range disks {
+			if used[j] || disk.SizeBytes < minSize {
+				continue
+			}
+			if requirements[i].Type != "" && diskType(disk) != requirements[i].Type {
+				continue
+			}
+			if found < 0 || disk.SizeBytes < disks[found].SizeBytes {
+				found = j
+			}
+		}
+		if found < 0 {
+			return i, false
+		}
+		used[found] = true
+	}
+	return 0, true
+}
+
+// diskType returns the type of an inspected disk.
+func diskType(disk bmh.Storage) bootstrapv1.DiskType {
+	switch {
+	case disk.Rotational:
+		return bootstrapv1.HDD
+	case strings.HasPrefix(disk.Name, "/dev/nvme") || strings.HasPrefix(disk.Name, "nvme"):
+		return bootstrapv1.NVME
+	default:
+		return bootstrapv1.SSD
+	}
+}
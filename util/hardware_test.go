@@ -0,0 +1,251 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	bmh "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	. "github.com/onsi/gomega"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestConvertRootDeviceHints(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(convertRootDeviceHints(nil)).To(BeNil())
+	g.Expect(convertRootDeviceHints(&bootstrapv1.RootDeviceHints{
+		DeviceName:         "/dev/sda",
+		HCTL:               "0:0:0:0",
+		Model:              "PERC H730",
+		Vendor:             "DELL",
+		SerialNumber:       "S3YJNX0K",
+		MinSizeGigabytes:   200,
+		WWN:                "0x4000cca77fc4dba1",
+		WWNWithExtension:   "0x4000cca77fc4dba1-ext",
+		WWNVendorExtension: "ext",
+		Rotational:         pointer.BoolPtr(false),
+	})).To(Equal(&bmh.RootDeviceHints{
+		DeviceName:         "/dev/sda",
+		HCTL:               "0:0:0:0",
+		Model:              "PERC H730",
+		Vendor:             "DELL",
+		SerialNumber:       "S3YJNX0K",
+		MinSizeGigabytes:   200,
+		WWN:                "0x4000cca77fc4dba1",
+		WWNWithExtension:   "0x4000cca77fc4dba1-ext",
+		WWNVendorExtension: "ext",
+		Rotational:         pointer.BoolPtr(false),
+	}))
+}
+
+func TestConvertRAID(t *testing.T) {
+	var tests = []struct {
+		name string
+		raid *bootstrapv1.RAID
+		want *bmh.RAIDConfig
+	}{
+		{
+			name: "no RAID",
+		},
+		{
+			// An empty configuration clears the RAID of the host
+			name: "no volumes",
+			raid: &bootstrapv1.RAID{},
+			want: &bmh.RAIDConfig{},
+		},
+		{
+			name: "hardware volumes",
+			raid: &bootstrapv1.RAID{HardwareRAIDVolumes: []bootstrapv1.HardwareRAIDVolume{
+				{Name: "root", Level: "1", SizeGibibytes: intPtr(200), Rotational: pointer.BoolPtr(false)},
+				{Level: "5", NumberOfPhysicalDisks: intPtr(4)},
+			}},
+			want: &bmh.RAIDConfig{HardwareRAIDVolumes: []bmh.HardwareRAIDVolume{
+				{Name: "root", Level: "1", SizeGibibytes: intPtr(200), Rotational: pointer.BoolPtr(false)},
+				{Level: "5", NumberOfPhysicalDisks: intPtr(4)},
+			}},
+		},
+		{
+			name: "software volumes",
+			raid: &bootstrapv1.RAID{SoftwareRAIDVolumes: []bootstrapv1.SoftwareRAIDVolume{
+				{Level: "1", SizeGibibytes: intPtr(100), PhysicalDisks: []bootstrapv1.RootDeviceHints{
+					{DeviceName: "/dev/sda"}, {DeviceName: "/dev/sdb"},
+				}},
+				{Level: "0"},
+			}},
+			want: &bmh.RAIDConfig{SoftwareRAIDVolumes: []bmh.SoftwareRAIDVolume{
+				{Level: "1", SizeGibibytes: intPtr(100), PhysicalDisks: []bmh.RootDeviceHints{
+					{DeviceName: "/dev/sda"}, {DeviceName: "/dev/sdb"},
+				}},
+				{Level: "0"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(convertRAID(tt.raid)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestSetHostHardware(t *testing.T) {
+	g := NewWithT(t)
+
+	host := &bmh.BareMetalHost{Spec: bmh.BareMetalHostSpec{
+		RootDeviceHints: &bmh.RootDeviceHints{DeviceName: "/dev/sdz"},
+		RAID:            &bmh.RAIDConfig{HardwareRAIDVolumes: []bmh.HardwareRAIDVolume{{Level: "0"}}},
+	}}
+	config := &bootstrapv1.NodeConfig{Spec: bootstrapv1.NodeConfigSpec{
+		Storage: &bootstrapv1.Storage{RootDeviceHints: &bootstrapv1.RootDeviceHints{DeviceName: "/dev/sda"}},
+		RAID:    &bootstrapv1.RAID{HardwareRAIDVolumes: []bootstrapv1.HardwareRAIDVolume{{Level: "1"}}},
+	}}
+	c := &ConfigManager{NodeConfig: config, Log: log.Log}
+	c.setHostHardware(host)
+	g.Expect(host.Spec.RootDeviceHints).To(Equal(&bmh.RootDeviceHints{DeviceName: "/dev/sda"}))
+	g.Expect(host.Spec.RAID).To(Equal(&bmh.RAIDConfig{HardwareRAIDVolumes: []bmh.HardwareRAIDVolume{{Level: "1"}}}))
+
+	// Settings removed from the NodeConfig are removed from the host
+	config.Spec.Storage = nil
+	config.Spec.RAID = nil
+	c.setHostHardware(host)
+	g.Expect(host.Spec.RootDeviceHints).To(BeNil())
+	g.Expect(host.Spec.RAID).To(BeNil())
+}
+
+func TestCheckHardwareRequirements(t *testing.T) {
+	host := &bmh.BareMetalHost{Status: bmh.BareMetalHostStatus{HardwareDetails: &bmh.HardwareDetails{
+		CPU:          bmh.CPU{Count: 32},
+		RAMMebibytes: 131072,
+		NIC: []bmh.NIC{
+			{Name: "eno1", SpeedGbps: 1},
+			{Name: "ens1f0", SpeedGbps: 25},
+			{Name: "ens1f1", SpeedGbps: 25},
+		},
+		Storage: []bmh.Storage{
+			{Name: "/dev/sda", Rotational: true, SizeBytes: 4000 * bmh.GibiByte},
+			{Name: "/dev/sdb", SizeBytes: 480 * bmh.GibiByte},
+			{Name: "/dev/nvme0n1", SizeBytes: 1600 * bmh.GibiByte},
+		},
+	}}}
+
+	var tests = []struct {
+		name         string
+		requirements bootstrapv1.HardwareRequirements
+		host         *bmh.BareMetalHost
+		inspected    bool
+		wantErr      string
+	}{
+		{
+			name:         "not inspected",
+			requirements: bootstrapv1.HardwareRequirements{MinCPUs: 1},
+			host:         &bmh.BareMetalHost{},
+		},
+		{
+			name: "met",
+			requirements: bootstrapv1.HardwareRequirements{
+				MinCPUs:         32,
+				MinRAMMebibytes: 131072,
+				MinNICs:         2,
+				MinNICSpeedGbps: 25,
+				Disks: []bootstrapv1.DiskRequirement{
+					{MinSizeGigabytes: 400},
+					{MinSizeGigabytes: 1000, Type: bootstrapv1.NVME},
+					{MinSizeGigabytes: 2000},
+				},
+			},
+			host:      host,
+			inspected: true,
+		},
+		{
+			name:         "too few CPUs",
+			requirements: bootstrapv1.HardwareRequirements{MinCPUs: 64},
+			host:         host,
+			inspected:    true,
+			wantErr:      "the host has 32 CPUs, less than the 64 required",
+		},
+		{
+			name:         "too little RAM",
+			requirements: bootstrapv1.HardwareRequirements{MinRAMMebibytes: 262144},
+			host:         host,
+			inspected:    true,
+			wantErr:      "the host has 131072 MiB of RAM, less than the 262144 MiB required",
+		},
+		{
+			name:         "too few fast NICs",
+			requirements: bootstrapv1.HardwareRequirements{MinNICs: 3, MinNICSpeedGbps: 10},
+			host:         host,
+			inspected:    true,
+			wantErr:      "the host has 2 NICs of at least 10 Gbps, less than the 3 required",
+		},
+		{
+			name:         "no NIC fast enough",
+			requirements: bootstrapv1.HardwareRequirements{MinNICSpeedGbps: 100},
+			host:         host,
+			inspected:    true,
+			wantErr:      "the host has 0 NICs of at least 100 Gbps, less than the 1 required",
+		},
+		{
+			name: "SSD taken by a typed requirement",
+			requirements: bootstrapv1.HardwareRequirements{Disks: []bootstrapv1.DiskRequirement{
+				{MinSizeGigabytes: 400},
+				{MinSizeGigabytes: 400, Type: bootstrapv1.SSD},
+				{MinSizeGigabytes: 2000},
+			}},
+			host:      host,
+			inspected: true,
+		},
+		{
+			name: "disk used twice",
+			requirements: bootstrapv1.HardwareRequirements{Disks: []bootstrapv1.DiskRequirement{
+				{MinSizeGigabytes: 1000, Type: bootstrapv1.NVME},
+				{MinSizeGigabytes: 500, Type: bootstrapv1.NVME},
+			}},
+			host:      host,
+			inspected: true,
+			wantErr:   "the host has no disk left for disk requirement 1, 500 GiB of NVME",
+		},
+		{
+			name: "disk too small",
+			requirements: bootstrapv1.HardwareRequirements{Disks: []bootstrapv1.DiskRequirement{
+				{MinSizeGigabytes: 8000},
+			}},
+			host:      host,
+			inspected: true,
+			wantErr:   "the host has no disk left for disk requirement 0, 8000 GiB of any type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			inspected, err := CheckHardwareRequirements(&tt.requirements, tt.host)
+			g.Expect(inspected).To(Equal(tt.inspected))
+			if tt.wantErr == "" {
+				g.Expect(err).NotTo(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(tt.wantErr))
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
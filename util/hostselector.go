@@ -0,0 +1,161 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"sort"
+
+	bmh "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClaimHost claims an available BareMetalHost matching the host selector and
+// the hardware requirements of the NodeConfig and records it in the status.
+// A host already consumed by the NodeConfig is taken back first. It returns
+// nil when no host can be claimed.
+//
+// The host is claimed by setting its consumerRef with a patch conditioned
+// on its resource version, so a host claimed by a concurrent reconcile
+// fails the patch and the next host is tried.
+func (c *ConfigManager) ClaimHost(ctx context.Context) (*bmh.BareMetalHost, error) {
+	selector, err := metav1.LabelSelectorAsSelector(c.NodeConfig.Spec.HostSelector.LabelSelector())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid hostSelector")
+	}
+	hosts := &bmh.BareMetalHostList{}
+	if err := c.client.List(ctx, hosts, client.InNamespace(c.NodeConfig.Namespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list the BareMetalHosts in namespace %s", c.NodeConfig.Namespace)
+	}
+
+	var candidates []*bmh.BareMetalHost
+	for i := range hosts.Items {
+		host := &hosts.Items[i]
+		if c.isConsumer(host) {
+			c.setHostRef(host)
+			return host, nil
+		}
+		if !HostClaimable(host, c.NodeConfig.Spec.HostSelector) {
+			continue
+		}
+		if requirements := c.NodeConfig.Spec.HardwareRequirements; requirements != nil {
+			if inspected, err := CheckHardwareRequirements(requirements, host); !inspected || err != nil {
+				continue
+			}
+		}
+		candidates = append(candidates, host)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+
+	for _, host := range candidates {
+		patch := client.MergeFromWithOptions(host.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		host.Spec.ConsumerRef = c.consumerRef()
+		if err := c.client.Patch(ctx, host, patch); err != nil {
+			if apierrors.IsConflict(err) || apierrors.IsNotFound(err) {
+				c.Log.Info("The BareMetalHost changed while claiming it. Trying the next one", "host", host.Name)
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to claim BareMetalHost %s/%s", host.Namespace, host.Name)
+		}
+		c.Log.Info("Claimed the BareMetalHost", "host", host.Name)
+		c.setHostRef(host)
+		return host, nil
+	}
+	return nil, nil
+}
+
+// HostClaimable returns true when the host matches the hardware profile of
+// the selector, and is available and consumed by nothing.
+func HostClaimable(host *bmh.BareMetalHost, selector *bootstrapv1.HostSelector) bool {
+	if host.Spec.ConsumerRef != nil || !host.DeletionTimestamp.IsZero() {
+		return false
+	}
+	if host.Spec.Image != nil || host.Spec.UserData != nil {
+		return false
+	}
+	if selector.HardwareProfile != "" && host.HardwareProfile() != selector.HardwareProfile {
+		return false
+	}
+	switch host.Status.Provisioning.State {
+	case bmh.StateReady, bmh.StateAvailable:
+	default:
+		return false
+	}
+	return host.Status.OperationalStatus == bmh.OperationalStatusOK
+}
+
+// HostSelects returns true when the labels of the host match the selector.
+func HostSelects(selector *bootstrapv1.HostSelector, host *bmh.BareMetalHost) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector.LabelSelector())
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(host.Labels))
+}
+
+// isConsumer returns true when the NodeConfig is the consumer of the host.
+func (c *ConfigManager) isConsumer(host *bmh.BareMetalHost) bool {
+	ref := host.Spec.ConsumerRef
+	return ref != nil && ref.Kind == "NodeConfig" && ref.Namespace == c.NodeConfig.Namespace &&
+		ref.Name == c.NodeConfig.Name && ref.UID == c.NodeConfig.UID
+}
+
+// consumerRef returns the reference to the NodeConfig set as the consumer
+// of its host.
+func (c *ConfigManager) consumerRef() *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: bootstrapv1.GroupVersion.String(),
+		Kind:       "NodeConfig",
+		Namespace:  c.NodeConfig.Namespace,
+		Name:       c.NodeConfig.Name,
+		UID:        c.NodeConfig.UID,
+	}
+}
+
+// setHostRef records the host in the status of the NodeConfig.
+func (c *ConfigManager) setHostRef(host *bmh.BareMetalHost) {
+	c.NodeConfig.Status.HostRef = &corev1.ObjectReference{
+		APIVersion: bmh.GroupVersion.String(),
+		Kind:       "BareMetalHost",
+		Namespace:  host.Namespace,
+		Name:       host.Name,
+		UID:        host.UID,
+	}
+}
+
+// releaseConsumerRef clears the consumerRef of a host consumed by the
+// NodeConfig, so it can be claimed again.
+func (c *ConfigManager) releaseConsumerRef(ctx context.Context, host *bmh.BareMetalHost) error {
+	if !c.isConsumer(host) {
+		return nil
+	}
+	patch := client.MergeFrom(host.DeepCopy())
+	host.Spec.ConsumerRef = nil
+	if err := c.client.Patch(ctx, host, patch); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to release BareMetalHost %s/%s", host.Namespace, host.Name)
+	}
+	return nil
+}
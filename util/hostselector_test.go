@@ -0,0 +1,183 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	bmh "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	. "github.com/onsi/gomega"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// claimingClient claims the first host it lists for another consumer, as a
+// concurrent reconcile would.
+type claimingClient struct {
+	client.Client
+	claimed bool
+}
+
+func (c *claimingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	hosts, ok := list.(*bmh.BareMetalHostList)
+	if !ok || c.claimed || len(hosts.Items) == 0 {
+		return nil
+	}
+	c.claimed = true
+	host := hosts.Items[0].DeepCopy()
+	host.Spec.ConsumerRef = &corev1.ObjectReference{Kind: "NodeConfig", Namespace: host.Namespace, Name: "other"}
+	return c.Client.Update(ctx, host)
+}
+
+func newTestHost(name string, labels map[string]string, state bmh.ProvisioningState) *bmh.BareMetalHost {
+	return &bmh.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels, UID: types.UID("uid-" + name)},
+		Status: bmh.BareMetalHostStatus{
+			OperationalStatus: bmh.OperationalStatusOK,
+			HardwareProfile:   "dell",
+			Provisioning:      bmh.ProvisionStatus{State: state},
+		},
+	}
+}
+
+func TestClaimHost(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	NewWithT(t).Expect(bmh.AddToScheme(scheme)).To(Succeed())
+	rack1 := map[string]string{"rack": "1"}
+	newConfig := func(name string) *bootstrapv1.NodeConfig {
+		return &bootstrapv1.NodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+			Spec: bootstrapv1.NodeConfigSpec{
+				HostSelector: &bootstrapv1.HostSelector{MatchLabels: rack1, HardwareProfile: "dell"},
+			},
+		}
+	}
+
+	t.Run("claims the first available host", func(t *testing.T) {
+		g := NewWithT(t)
+
+		consumed := newTestHost("host-0", rack1, bmh.StateAvailable)
+		consumed.Spec.ConsumerRef = &corev1.ObjectReference{Kind: "NodeConfig", Namespace: "default", Name: "other"}
+		otherRack := newTestHost("host-1", map[string]string{"rack": "2"}, bmh.StateAvailable)
+		provisioned := newTestHost("host-2", rack1, bmh.StateProvisioned)
+		otherProfile := newTestHost("host-3", rack1, bmh.StateAvailable)
+		otherProfile.Status.HardwareProfile = "unknown"
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			consumed, otherRack, provisioned, otherProfile,
+			newTestHost("host-5", rack1, bmh.StateReady),
+			newTestHost("host-4", rack1, bmh.StateAvailable),
+		).Build()
+
+		config := newConfig("worker-0")
+		c := &ConfigManager{client: cl, NodeConfig: config, Log: log.Log}
+		host, err := c.ClaimHost(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(host.Name).To(Equal("host-4"))
+		g.Expect(config.Status.HostRef.Name).To(Equal("host-4"))
+
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+		g.Expect(host.Spec.ConsumerRef.Name).To(Equal("worker-0"))
+		g.Expect(host.Spec.ConsumerRef.UID).To(BeEquivalentTo("uid-worker-0"))
+
+		// The claimed host is found through the status from now on
+		found, err := getHost(ctx, config, cl, log.Log)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(found.Name).To(Equal("host-4"))
+
+		// The host is taken back when the status was lost
+		config.Status.HostRef = nil
+		host, err = c.ClaimHost(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(host.Name).To(Equal("host-4"))
+
+		// Another NodeConfig gets the next host, then none is left
+		c = &ConfigManager{client: cl, NodeConfig: newConfig("worker-1"), Log: log.Log}
+		host, err = c.ClaimHost(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(host.Name).To(Equal("host-5"))
+		c = &ConfigManager{client: cl, NodeConfig: newConfig("worker-2"), Log: log.Log}
+		host, err = c.ClaimHost(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(host).To(BeNil())
+		g.Expect(c.NodeConfig.Status.HostRef).To(BeNil())
+	})
+
+	t.Run("skips the hosts not meeting the hardware requirements", func(t *testing.T) {
+		g := NewWithT(t)
+
+		small := newTestHost("host-0", rack1, bmh.StateAvailable)
+		small.Status.HardwareDetails = &bmh.HardwareDetails{CPU: bmh.CPU{Count: 8}}
+		large := newTestHost("host-1", rack1, bmh.StateAvailable)
+		large.Status.HardwareDetails = &bmh.HardwareDetails{CPU: bmh.CPU{Count: 64}}
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(small, large).Build()
+
+		config := newConfig("worker-0")
+		config.Spec.HardwareRequirements = &bootstrapv1.HardwareRequirements{MinCPUs: 32}
+		c := &ConfigManager{client: cl, NodeConfig: config, Log: log.Log}
+		host, err := c.ClaimHost(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(host.Name).To(Equal("host-1"))
+	})
+
+	t.Run("skips a host claimed concurrently", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cl := &claimingClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			newTestHost("host-0", rack1, bmh.StateAvailable),
+			newTestHost("host-1", rack1, bmh.StateAvailable),
+		).Build()}
+
+		c := &ConfigManager{client: cl, NodeConfig: newConfig("worker-0"), Log: log.Log}
+		host, err := c.ClaimHost(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(host.Name).To(Equal("host-1"))
+
+		host = &bmh.BareMetalHost{}
+		g.Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "host-0"}, host)).To(Succeed())
+		g.Expect(host.Spec.ConsumerRef.Name).To(Equal("other"))
+	})
+
+	t.Run("releases the host on deletion", func(t *testing.T) {
+		g := NewWithT(t)
+
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			newTestHost("host-0", rack1, bmh.StateAvailable),
+		).Build()
+		c := &ConfigManager{client: cl, NodeConfig: newConfig("worker-0"), Log: log.Log}
+		_, err := c.ClaimHost(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		released, err := c.ReleaseHost(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(released).To(BeTrue())
+		host := &bmh.BareMetalHost{}
+		g.Expect(cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "host-0"}, host)).To(Succeed())
+		g.Expect(host.Spec.ConsumerRef).To(BeNil())
+	})
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignition
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	"github.com/tmax-cloud/nodeconfig-operator/util/cloudinit"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// commandsScriptPath is where the NodeConfig commands are written.
+	commandsScriptPath = "/usr/local/bin/nodeconfig-commands.sh"
+
+	// commandsStampPath marks the commands as done, so they only run on
+	// first boot like cloud-init runcmd.
+	commandsStampPath = "/var/lib/nodeconfig/commands.done"
+
+	commandsUnitName = "nodeconfig-commands.service"
+
+	commandsUnit = `[Unit]
+Description=Run the NodeConfig commands on first boot
+ConditionPathExists=!` + commandsStampPath + `
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=` + commandsScriptPath + `
+ExecStartPost=/bin/sh -c 'mkdir -p $(dirname ` + commandsStampPath + `) && touch ` + commandsStampPath + `'
+
+[Install]
+WantedBy=multi-user.target
+`
+
+	chronyConfPath = "/etc/chrony.conf"
+
+	sudoersDir = "/etc/sudoers.d"
+)
+
+// NodeInput defines the context to generate a node Ignition config.
+type NodeInput struct {
+	CloudInitCommands []string
+	AdditionalFiles   []bootstrapv1.File
+	Users             []bootstrapv1.User
+	NTP               *bootstrapv1.NTP
+
+	ClusterConfiguration string
+	InitConfiguration    string
+	UploadCerts          bool
+	JoinConfiguration    string
+}
+
+// NewNode returns the Ignition config to be used on a node instance.
+func NewNode(input *NodeInput) ([]byte, error) {
+	config := Config{
+		Ignition: Ignition{Version: Version},
+	}
+
+	for _, f := range input.AdditionalFiles {
+		file, err := convertFile(f)
+		if err != nil {
+			return nil, err
+		}
+		config.Storage.Files = append(config.Storage.Files, file)
+	}
+
+	for _, u := range input.Users {
+		config.Passwd.Users = append(config.Passwd.Users, convertUser(u))
+		if u.Sudo != nil {
+			// Ignition has no sudo support, so grant it with a drop-in
+			config.Storage.Files = append(config.Storage.Files,
+				plainFile(sudoersDir+"/"+u.Name, 0440, fmt.Sprintf("%s %s\n", u.Name, *u.Sudo)))
+		}
+	}
+
+	commands := append([]string{}, input.CloudInitCommands...)
+	switch {
+	case input.InitConfiguration != "":
+		config.Storage.Files = append(config.Storage.Files,
+			plainFile(cloudinit.KubeadmInitConfigPath, 0640,
+				"---\n"+input.ClusterConfiguration+"\n---\n"+input.InitConfiguration))
+		command := cloudinit.KubeadmInitCommand
+		if input.UploadCerts {
+			command += cloudinit.KubeadmUploadCertsFlag
+		}
+		commands = append(commands, command)
+	case input.JoinConfiguration != "":
+		config.Storage.Files = append(config.Storage.Files,
+			plainFile(cloudinit.KubeadmJoinConfigPath, 0640, "---\n"+input.JoinConfiguration))
+		commands = append(commands, cloudinit.KubeadmJoinCommand)
+	}
+
+	if len(commands) > 0 {
+		script := "#!/bin/bash\nset -e\n" + strings.Join(commands, "\n") + "\n"
+		config.Storage.Files = append(config.Storage.Files, plainFile(commandsScriptPath, 0755, script))
+		config.Systemd.Units = append(config.Systemd.Units, Unit{
+			Name:     commandsUnitName,
+			Enabled:  pointer.BoolPtr(true),
+			Contents: pointer.StringPtr(commandsUnit),
+		})
+	}
+
+	if input.NTP != nil {
+		config.Storage.Files = append(config.Storage.Files, plainFile(chronyConfPath, 0644, chronyConf(input.NTP)))
+		if input.NTP.Enabled != nil && *input.NTP.Enabled {
+			config.Systemd.Units = append(config.Systemd.Units, Unit{
+				Name:    "chronyd.service",
+				Enabled: pointer.BoolPtr(true),
+			})
+		}
+	}
+
+	out, err := json.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal Ignition config")
+	}
+	return out, nil
+}
+
+// convertFile maps a cloud-init file to an Ignition file. The contents are
+// always handed over as a base64 data URL; gzip encoded contents are
+// decompressed by Ignition.
+func convertFile(f bootstrapv1.File) (File, error) {
+	file := File{
+		Path:      f.Path,
+		Overwrite: pointer.BoolPtr(true),
+	}
+
+	switch f.Encoding {
+	case bootstrapv1.Base64:
+		file.Contents.Source = dataURL(f.Content, true)
+	case bootstrapv1.Gzip:
+		file.Contents.Compression = "gzip"
+		file.Contents.Source = dataURL(f.Content, false)
+	case bootstrapv1.GzipBase64:
+		file.Contents.Compression = "gzip"
+		file.Contents.Source = dataURL(f.Content, true)
+	default:
+		file.Contents.Source = dataURL(f.Content, false)
+	}
+
+	if f.Owner != "" {
+		owner := strings.SplitN(f.Owner, ":", 2)
+		file.User = &NodeUser{Name: owner[0]}
+		if len(owner) == 2 && owner[1] != "" {
+			file.Group = &NodeGroup{Name: owner[1]}
+		}
+	}
+
+	if f.Permissions != "" {
+		mode, err := strconv.ParseInt(f.Permissions, 8, 32)
+		if err != nil {
+			return File{}, errors.Wrapf(err, "invalid permissions %q for file %s", f.Permissions, f.Path)
+		}
+		m := int(mode)
+		file.Mode = &m
+	}
+
+	return file, nil
+}
+
+// convertUser maps a cloud-init user to an Ignition user. LockPassword and
+// Inactive have no Ignition equivalent and are ignored.
+func convertUser(u bootstrapv1.User) PasswdUser {
+	user := PasswdUser{
+		Name:              u.Name,
+		Gecos:             u.Gecos,
+		HomeDir:           u.HomeDir,
+		PasswordHash:      u.Passwd,
+		PrimaryGroup:      u.PrimaryGroup,
+		Shell:             u.Shell,
+		SSHAuthorizedKeys: u.SSHAuthorizedKeys,
+	}
+	if u.Groups != nil {
+		for _, g := range strings.Split(*u.Groups, ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				user.Groups = append(user.Groups, g)
+			}
+		}
+	}
+	return user
+}
+
+// chronyConf renders a chrony config using the given NTP servers.
+func chronyConf(ntp *bootstrapv1.NTP) string {
+	var b strings.Builder
+	for _, s := range ntp.Servers {
+		fmt.Fprintf(&b, "server %s iburst\n", s)
+	}
+	b.WriteString("driftfile /var/lib/chrony/drift\n")
+	b.WriteString("makestep 1.0 3\n")
+	b.WriteString("rtcsync\n")
+	b.WriteString("logdir /var/log/chrony\n")
+	return b.String()
+}
+
+func plainFile(path string, mode int, content string) File {
+	return File{
+		Path:      path,
+		Overwrite: pointer.BoolPtr(true),
+		Mode:      &mode,
+		Contents:  FileContents{Source: dataURL(content, false)},
+	}
+}
+
+// dataURL returns a base64 data URL for the content, which is encoded first
+// unless it already is.
+func dataURL(content string, encoded bool) string {
+	if !encoded {
+		content = base64.StdEncoding.EncodeToString([]byte(content))
+	} else {
+		content = strings.Join(strings.Fields(content), "")
+	}
+	return "data:;base64," + content
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignition
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	infrav1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+)
+
+func render(g *WithT, input *NodeInput) Config {
+	out, err := NewNode(input)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	config := Config{}
+	g.Expect(json.Unmarshal(out, &config)).To(Succeed())
+	g.Expect(config.Ignition.Version).To(Equal(Version))
+	return config
+}
+
+func TestNewNodeFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	config := render(g, &NodeInput{
+		AdditionalFiles: []infrav1.File{
+			{
+				Path:        "/tmp/my-path",
+				Owner:       "core:wheel",
+				Permissions: "0640",
+				Encoding:    infrav1.Base64,
+				Content:     "aGk=",
+			},
+			{
+				Path:    "/tmp/my-other-path",
+				Content: "hi",
+			},
+			{
+				Path:     "/tmp/my-gzip-path",
+				Encoding: infrav1.GzipBase64,
+				Content:  "H4sIAAAAAAAAA8vIBACsKpPYAgAAAA==",
+			},
+		},
+	})
+
+	g.Expect(config.Storage.Files).To(HaveLen(3))
+
+	f := config.Storage.Files[0]
+	g.Expect(f.Path).To(Equal("/tmp/my-path"))
+	g.Expect(f.Contents.Source).To(Equal("data:;base64,aGk="))
+	g.Expect(f.Contents.Compression).To(BeEmpty())
+	g.Expect(f.User).To(Equal(&NodeUser{Name: "core"}))
+	g.Expect(f.Group).To(Equal(&NodeGroup{Name: "wheel"}))
+	g.Expect(f.Mode).To(Equal(intPtr(0640)))
+
+	f = config.Storage.Files[1]
+	g.Expect(f.Contents.Source).To(Equal("data:;base64,aGk="))
+	g.Expect(f.User).To(BeNil())
+	g.Expect(f.Mode).To(BeNil())
+
+	f = config.Storage.Files[2]
+	g.Expect(f.Contents.Compression).To(Equal("gzip"))
+	g.Expect(f.Contents.Source).To(Equal("data:;base64,H4sIAAAAAAAAA8vIBACsKpPYAgAAAA=="))
+}
+
+func TestNewNodeInvalidPermissions(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := NewNode(&NodeInput{
+		AdditionalFiles: []infrav1.File{
+			{Path: "/tmp/my-path", Permissions: "rw-r-----", Content: "hi"},
+		},
+	})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestNewNodeUsers(t *testing.T) {
+	g := NewWithT(t)
+
+	config := render(g, &NodeInput{
+		Users: []infrav1.User{
+			{
+				Name:              "tmax",
+				Groups:            pointer.StringPtr("wheel, docker"),
+				Passwd:            pointer.StringPtr("$6$hash"),
+				Sudo:              pointer.StringPtr("ALL=(ALL) NOPASSWD:ALL"),
+				SSHAuthorizedKeys: []string{"ssh-rsa XXX"},
+			},
+		},
+	})
+
+	g.Expect(config.Passwd.Users).To(HaveLen(1))
+	u := config.Passwd.Users[0]
+	g.Expect(u.Name).To(Equal("tmax"))
+	g.Expect(u.Groups).To(Equal([]string{"wheel", "docker"}))
+	g.Expect(u.PasswordHash).To(Equal(pointer.StringPtr("$6$hash")))
+	g.Expect(u.SSHAuthorizedKeys).To(Equal([]string{"ssh-rsa XXX"}))
+
+	g.Expect(config.Storage.Files).To(HaveLen(1))
+	g.Expect(config.Storage.Files[0].Path).To(Equal("/etc/sudoers.d/tmax"))
+	g.Expect(config.Storage.Files[0].Mode).To(Equal(intPtr(0440)))
+	// "tmax ALL=(ALL) NOPASSWD:ALL\n"
+	g.Expect(config.Storage.Files[0].Contents.Source).To(Equal("data:;base64,dG1heCBBTEw9KEFMTCkgTk9QQVNTV0Q6QUxMCg=="))
+}
+
+func TestNewNodeCommands(t *testing.T) {
+	g := NewWithT(t)
+
+	config := render(g, &NodeInput{
+		CloudInitCommands: []string{"echo hello", "systemctl restart sshd"},
+	})
+
+	g.Expect(config.Storage.Files).To(HaveLen(1))
+	g.Expect(config.Storage.Files[0].Path).To(Equal(commandsScriptPath))
+	g.Expect(config.Storage.Files[0].Mode).To(Equal(intPtr(0755)))
+
+	g.Expect(config.Systemd.Units).To(HaveLen(1))
+	unit := config.Systemd.Units[0]
+	g.Expect(unit.Name).To(Equal(commandsUnitName))
+	g.Expect(unit.Enabled).To(Equal(pointer.BoolPtr(true)))
+	g.Expect(*unit.Contents).To(ContainSubstring("Type=oneshot"))
+	g.Expect(*unit.Contents).To(ContainSubstring("ExecStart=" + commandsScriptPath))
+}
+
+func TestNewNodeNTP(t *testing.T) {
+	g := NewWithT(t)
+
+	config := render(g, &NodeInput{
+		NTP: &infrav1.NTP{
+			Servers: []string{"time.example.com"},
+			Enabled: pointer.BoolPtr(true),
+		},
+	})
+
+	g.Expect(config.Storage.Files).To(HaveLen(1))
+	g.Expect(config.Storage.Files[0].Path).To(Equal(chronyConfPath))
+	g.Expect(chronyConf(&infrav1.NTP{Servers: []string{"time.example.com"}})).
+		To(HavePrefix("server time.example.com iburst\n"))
+
+	g.Expect(config.Systemd.Units).To(ConsistOf(Unit{Name: "chronyd.service", Enabled: pointer.BoolPtr(true)}))
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignition
+
+// The types below are the subset of the Ignition v3 config spec rendered by
+// this package. See https://coreos.github.io/ignition/configuration-v3_1/
+
+// Version is the Ignition config spec version rendered by this package.
+const Version = "3.1.0"
+
+// Config is the top level Ignition config.
+type Config struct {
+	Ignition Ignition `json:"ignition"`
+	Passwd   Passwd   `json:"passwd"`
+	Storage  Storage  `json:"storage"`
+	Systemd  Systemd  `json:"systemd"`
+}
+
+// Ignition holds the metadata of the config.
+type Ignition struct {
+	Version string `json:"version"`
+}
+
+// Passwd holds the users to create.
+type Passwd struct {
+	Users []PasswdUser `json:"users,omitempty"`
+}
+
+// PasswdUser is a user to create.
+type PasswdUser struct {
+	Name              string   `json:"name"`
+	Gecos             *string  `json:"gecos,omitempty"`
+	Groups            []string `json:"groups,omitempty"`
+	HomeDir           *string  `json:"homeDir,omitempty"`
+	PasswordHash      *string  `json:"passwordHash,omitempty"`
+	PrimaryGroup      *string  `json:"primaryGroup,omitempty"`
+	Shell             *string  `json:"shell,omitempty"`
+	SSHAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
+}
+
+// Storage holds the files to write.
+type Storage struct {
+	Files []File `json:"files,omitempty"`
+}
+
+// File is a file to write to disk.
+type File struct {
+	Path      string       `json:"path"`
+	Overwrite *bool        `json:"overwrite,omitempty"`
+	User      *NodeUser    `json:"user,omitempty"`
+	Group     *NodeGroup   `json:"group,omitempty"`
+	Mode      *int         `json:"mode,omitempty"`
+	Contents  FileContents `json:"contents"`
+}
+
+// NodeUser is the owner of a file.
+type NodeUser struct {
+	Name string `json:"name"`
+}
+
+// NodeGroup is the group of a file.
+type NodeGroup struct {
+	Name string `json:"name"`
+}
+
+// FileContents holds the contents of a file as a data URL.
+type FileContents struct {
+	Compression string `json:"compression,omitempty"`
+	Source      string `json:"source"`
+}
+
+// Systemd holds the units to write and enable.
+type Systemd struct {
+	Units []Unit `json:"units,omitempty"`
+}
+
+// Unit is a systemd unit.
+type Unit struct {
+	Name     string  `json:"name"`
+	Enabled  *bool   `json:"enabled,omitempty"`
+	Contents *string `json:"contents,omitempty"`
+}
@@ -0,0 +1,399 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]`)
+
+// poolInterface is an interface of the network configuration that takes its
+// address from an IPPool.
+type poolInterface struct {
+	name   string
+	config *bootstrapv1.InterfaceConfig
+}
+
+// ipClaimName returns the name of the IPClaim of the interface.
+func ipClaimName(nodeConfigName, interfaceName string) string {
+	return nodeConfigName + "-" + sanitizeName(interfaceName)
+}
+
+// sanitizeName turns an interface name into a valid object name part.
+func sanitizeName(name string) string {
+	return invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+}
+
+// withPoolAddresses returns a copy of the network configuration with the
+// addresses allocated from the IPPools filled in. It claims an address for
+// every interface referring to an IPPool, and releases the claims that are
+// not needed anymore.
+func (c *ConfigManager) withPoolAddresses(ctx context.Context, network *bootstrapv1.Network) (*bootstrapv1.Network, error) {
+	var interfaces []poolInterface
+	if network != nil {
+		network = network.DeepCopy()
+		for i := range network.Ethernets {
+			interfaces = append(interfaces, poolInterface{network.Ethernets[i].Name, &network.Ethernets[i].InterfaceConfig})
+		}
+		for i := range network.Bonds {
+			interfaces = append(interfaces, poolInterface{network.Bonds[i].Name, &network.Bonds[i].InterfaceConfig})
+		}
+		for i := range network.VLANs {
+			interfaces = append(interfaces, poolInterface{network.VLANs[i].Name, &network.VLANs[i].InterfaceConfig})
+		}
+	}
+
+	claimed := map[string]bool{}
+	for _, iface := range interfaces {
+		if iface.config.IPPoolRef == nil {
+			continue
+		}
+		claim, err := c.claimAddress(ctx, iface.name, iface.config.IPPoolRef.Name)
+		if err != nil {
+			return nil, err
+		}
+		claimed[claim.Name] = true
+
+		iface.config.Addresses = append(iface.config.Addresses, claim.Status.Address)
+		if claim.Status.Gateway != "" && iface.config.Gateway4 == "" && iface.config.Gateway6 == "" {
+			if net.ParseIP(claim.Status.Gateway).To4() != nil {
+				iface.config.Gateway4 = claim.Status.Gateway
+			} else {
+				iface.config.Gateway6 = claim.Status.Gateway
+			}
+		}
+		if len(claim.Status.Nameservers) > 0 && iface.config.Nameservers == nil {
+			iface.config.Nameservers = &bootstrapv1.Nameservers{Addresses: claim.Status.Nameservers}
+		}
+	}
+
+	if err := c.releaseIPClaims(ctx, claimed); err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
+// claimAddress returns the IPClaim of the interface once an address is
+// allocated to it from the IPPool, creating the claim if needed.
+func (c *ConfigManager) claimAddress(ctx context.Context, interfaceName, poolName string) (*bootstrapv1.IPClaim, error) {
+	claim := &bootstrapv1.IPClaim{}
+	key := client.ObjectKey{Namespace: c.NodeConfig.Namespace, Name: ipClaimName(c.NodeConfig.Name, interfaceName)}
+	err := c.client.Get(ctx, key, claim)
+	switch {
+	case err == nil && !metav1.IsControlledBy(claim, c.NodeConfig):
+		return nil, errors.Errorf("IPClaim %s/%s is not owned by the NodeConfig. It may hold the address of a detached host", key.Namespace, key.Name)
+	case err == nil && claim.Spec.PoolRef.Name == poolName:
+	case err == nil:
+		// The interface moved to another pool
+		if err := c.releaseIPClaim(ctx, claim); err != nil {
+			return nil, err
+		}
+		if claim, err = c.createIPClaim(ctx, key, interfaceName, poolName); err != nil {
+			return nil, err
+		}
+	case apierrors.IsNotFound(err):
+		if claim, err = c.createIPClaim(ctx, key, interfaceName, poolName); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Wrapf(err, "failed to get IPClaim %s/%s", key.Namespace, key.Name)
+	}
+
+	if claim.Status.Address != "" {
+		return claim, nil
+	}
+
+	pool, address, err := c.allocateAddress(ctx, claim)
+	if err != nil {
+		return nil, err
+	}
+	claim.Status = bootstrapv1.IPClaimStatus{
+		Address:     address,
+		Gateway:     pool.Spec.Gateway,
+		Nameservers: pool.Spec.Nameservers,
+	}
+	if err := c.client.Status().Update(ctx, claim); err != nil {
+		return nil, errors.Wrapf(err, "failed to update IPClaim %s/%s", key.Namespace, key.Name)
+	}
+	c.Log.Info("Allocated an address", "pool", pool.Name, "claim", claim.Name, "address", address)
+	return claim, nil
+}
+
+func (c *ConfigManager) createIPClaim(ctx context.Context, key client.ObjectKey, interfaceName, poolName string) (*bootstrapv1.IPClaim, error) {
+	claim := &bootstrapv1.IPClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels: map[string]string{
+				bootstrapv1.IPClaimNodeConfigLabel: c.NodeConfig.Name,
+				bootstrapv1.IPClaimInterfaceLabel:  sanitizeName(interfaceName),
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: bootstrapv1.GroupVersion.String(),
+					Kind:       "NodeConfig",
+					Name:       c.NodeConfig.Name,
+					UID:        c.NodeConfig.UID,
+					Controller: pointer.BoolPtr(true),
+				},
+			},
+		},
+		Spec: bootstrapv1.IPClaimSpec{
+			PoolRef: corev1.LocalObjectReference{Name: poolName},
+		},
+	}
+	if err := c.client.Create(ctx, claim); err != nil {
+		return nil, errors.Wrapf(err, "failed to create IPClaim %s/%s", key.Namespace, key.Name)
+	}
+	return claim, nil
+}
+
+// allocateAddress allocates an address of the IPPool to the claim, or
+// returns the one already allocated. The allocation is recorded in the pool
+// status, and the update is retried on conflicts, so concurrent allocations
+// never hand out the same address.
+func (c *ConfigManager) allocateAddress(ctx context.Context, claim *bootstrapv1.IPClaim) (*bootstrapv1.IPPool, string, error) {
+	pool := &bootstrapv1.IPPool{}
+	key := client.ObjectKey{Namespace: claim.Namespace, Name: claim.Spec.PoolRef.Name}
+	var address string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := c.client.Get(ctx, key, pool); err != nil {
+			return err
+		}
+		_, subnet, err := net.ParseCIDR(pool.Spec.CIDR)
+		if err != nil {
+			return errors.Wrapf(err, "invalid cidr of IPPool %s", pool.Name)
+		}
+		prefix, _ := subnet.Mask.Size()
+
+		if ip, ok := pool.Status.Allocations[claim.Name]; ok {
+			address = fmt.Sprintf("%s/%d", ip, prefix)
+			return nil
+		}
+
+		ip, err := nextFreeAddress(pool)
+		if err != nil {
+			return err
+		}
+		if pool.Status.Allocations == nil {
+			pool.Status.Allocations = map[string]string{}
+		}
+		pool.Status.Allocations[claim.Name] = ip.String()
+		if err := c.client.Status().Update(ctx, pool); err != nil {
+			return err
+		}
+		address = fmt.Sprintf("%s/%d", ip, prefix)
+		return nil
+	})
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to allocate an address from IPPool %s/%s", key.Namespace, key.Name)
+	}
+	return pool, address, nil
+}
+
+// releaseIPClaims releases the IPClaims of the NodeConfig except the given
+// ones.
+func (c *ConfigManager) releaseIPClaims(ctx context.Context, keep map[string]bool) error {
+	claims := &bootstrapv1.IPClaimList{}
+	if err := c.client.List(ctx, claims, client.InNamespace(c.NodeConfig.Namespace),
+		client.MatchingLabels{bootstrapv1.IPClaimNodeConfigLabel: c.NodeConfig.Name}); err != nil {
+		return errors.Wrap(err, "failed to list IPClaims")
+	}
+	for i := range claims.Items {
+		if keep[claims.Items[i].Name] {
+			continue
+		}
+		if err := c.releaseIPClaim(ctx, &claims.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseIPClaims returns the addresses allocated to the NodeConfig to their
+// IPPools and deletes its IPClaims.
+func (c *ConfigManager) ReleaseIPClaims(ctx context.Context) error {
+	return c.releaseIPClaims(ctx, nil)
+}
+
+// OrphanIPClaims keeps the addresses allocated to the NodeConfig for a host
+// that goes on using them. The IPClaims lose their owner reference and their
+// NodeConfig label, so they outlive the NodeConfig and the addresses are not
+// handed out again.
+func (c *ConfigManager) OrphanIPClaims(ctx context.Context) error {
+	claims := &bootstrapv1.IPClaimList{}
+	if err := c.client.List(ctx, claims, client.InNamespace(c.NodeConfig.Namespace),
+		client.MatchingLabels{bootstrapv1.IPClaimNodeConfigLabel: c.NodeConfig.Name}); err != nil {
+		return errors.Wrap(err, "failed to list IPClaims")
+	}
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		patch := client.MergeFrom(claim.DeepCopy())
+		var refs []metav1.OwnerReference
+		for _, ref := range claim.OwnerReferences {
+			if ref.UID != c.NodeConfig.UID {
+				refs = append(refs, ref)
+			}
+		}
+		claim.OwnerReferences = refs
+		delete(claim.Labels, bootstrapv1.IPClaimNodeConfigLabel)
+		if err := c.client.Patch(ctx, claim, patch); err != nil {
+			return errors.Wrapf(err, "failed to orphan IPClaim %s/%s", claim.Namespace, claim.Name)
+		}
+		c.Log.Info("Kept an address for the detached host", "pool", claim.Spec.PoolRef.Name, "claim", claim.Name)
+	}
+	return nil
+}
+
+// releaseIPClaim removes the allocation of the claim from its IPPool and
+// deletes the claim.
+func (c *ConfigManager) releaseIPClaim(ctx context.Context, claim *bootstrapv1.IPClaim) error {
+	key := client.ObjectKey{Namespace: claim.Namespace, Name: claim.Spec.PoolRef.Name}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pool := &bootstrapv1.IPPool{}
+		if err := c.client.Get(ctx, key, pool); apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, ok := pool.Status.Allocations[claim.Name]; !ok {
+			return nil
+		}
+		delete(pool.Status.Allocations, claim.Name)
+		return c.client.Status().Update(ctx, pool)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to release the address of IPClaim %s from IPPool %s", claim.Name, key.Name)
+	}
+
+	if err := c.client.Delete(ctx, claim); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete IPClaim %s/%s", claim.Namespace, claim.Name)
+	}
+	c.Log.Info("Released an address", "pool", key.Name, "claim", claim.Name)
+	return nil
+}
+
+// ipRange is an inclusive range of addresses.
+type ipRange struct {
+	start, end net.IP
+}
+
+func (r ipRange) contains(ip net.IP) bool {
+	return bytes.Compare(ip, r.start) >= 0 && bytes.Compare(ip, r.end) <= 0
+}
+
+// nextFreeAddress returns the lowest address of the pool that is neither
+// allocated, excluded nor the gateway. The network and broadcast addresses
+// of an IPv4 subnet are skipped.
+func nextFreeAddress(pool *bootstrapv1.IPPool) (net.IP, error) {
+	_, subnet, err := net.ParseCIDR(pool.Spec.CIDR)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid cidr of IPPool %s", pool.Name)
+	}
+
+	excluded, err := parseExclusions(pool)
+	if err != nil {
+		return nil, err
+	}
+	allocated := map[string]bool{}
+	for _, ip := range pool.Status.Allocations {
+		allocated[ip] = true
+	}
+
+	isIPv4 := subnet.IP.To4() != nil
+	for ip := nextIP(subnet.IP); subnet.Contains(ip); ip = nextIP(ip) {
+		if isIPv4 && !subnet.Contains(nextIP(ip)) {
+			// The broadcast address
+			break
+		}
+		if allocated[ip.String()] || isExcluded(ip, excluded) {
+			continue
+		}
+		return ip, nil
+	}
+	return nil, errors.Errorf("IPPool %s has no free address", pool.Name)
+}
+
+// parseExclusions returns the ranges of the pool that are not allocated,
+// including its gateway.
+func parseExclusions(pool *bootstrapv1.IPPool) ([]ipRange, error) {
+	exclude := pool.Spec.Exclude
+	if pool.Spec.Gateway != "" {
+		exclude = append([]string{pool.Spec.Gateway}, exclude...)
+	}
+
+	var ranges []ipRange
+	for _, e := range exclude {
+		bounds := strings.SplitN(e, "-", 2)
+		start := normalizeIP(net.ParseIP(strings.TrimSpace(bounds[0])))
+		end := start
+		if len(bounds) == 2 {
+			end = normalizeIP(net.ParseIP(strings.TrimSpace(bounds[1])))
+		}
+		if start == nil || end == nil || len(start) != len(end) {
+			return nil, errors.Errorf("invalid exclusion %q of IPPool %s", e, pool.Name)
+		}
+		ranges = append(ranges, ipRange{start: start, end: end})
+	}
+	return ranges, nil
+}
+
+func isExcluded(ip net.IP, ranges []ipRange) bool {
+	for _, r := range ranges {
+		if len(ip) == len(r.start) && r.contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeIP returns IPv4 addresses in their 4-byte form, so they compare
+// with the addresses of the subnet.
+func normalizeIP(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip
+}
+
+// nextIP returns the address following ip.
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestNextFreeAddress(t *testing.T) {
+	var tests = []struct {
+		name    string
+		spec    bootstrapv1.IPPoolSpec
+		alloc   map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "first address",
+			spec: bootstrapv1.IPPoolSpec{CIDR: "192.168.100.0/24"},
+			want: "192.168.100.1",
+		},
+		{
+			name: "skips the gateway and the exclusions",
+			spec: bootstrapv1.IPPoolSpec{
+				CIDR:    "192.168.100.0/24",
+				Gateway: "192.168.100.1",
+				Exclude: []string{"192.168.100.2-192.168.100.10", "192.168.100.12"},
+			},
+			alloc: map[string]string{"other": "192.168.100.11"},
+			want:  "192.168.100.13",
+		},
+		{
+			name:    "exhausted",
+			spec:    bootstrapv1.IPPoolSpec{CIDR: "192.168.100.0/30", Gateway: "192.168.100.1"},
+			alloc:   map[string]string{"other": "192.168.100.2"},
+			wantErr: true,
+		},
+		{
+			name: "IPv6",
+			spec: bootstrapv1.IPPoolSpec{CIDR: "fd00::/64", Exclude: []string{"fd00::1-fd00::ff"}},
+			want: "fd00::100",
+		},
+		{
+			name:    "invalid cidr",
+			spec:    bootstrapv1.IPPoolSpec{CIDR: "192.168.100.0"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid exclusion",
+			spec:    bootstrapv1.IPPoolSpec{CIDR: "192.168.100.0/24", Exclude: []string{"192.168.100.1-fd00::1"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			pool := &bootstrapv1.IPPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool"},
+				Spec:       tt.spec,
+				Status:     bootstrapv1.IPPoolStatus{Allocations: tt.alloc},
+			}
+			ip, err := nextFreeAddress(pool)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(ip.String()).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestPoolAddresses(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+	pool := &bootstrapv1.IPPool{
+		ObjectMeta: metav1.ObjectMeta{Name: "pool", Namespace: "default"},
+		Spec: bootstrapv1.IPPoolSpec{
+			CIDR:        "192.168.100.0/24",
+			Gateway:     "192.168.100.1",
+			Nameservers: []string{"192.168.100.2"},
+			Exclude:     []string{"192.168.100.2"},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pool).Build()
+
+	network := &bootstrapv1.Network{
+		Ethernets: []bootstrapv1.Ethernet{
+			{
+				Name:            "eno1",
+				MACAddress:      "00:5c:52:31:3a:9c",
+				InterfaceConfig: bootstrapv1.InterfaceConfig{IPPoolRef: &corev1.LocalObjectReference{Name: "pool"}},
+			},
+		},
+	}
+	newConfigManager := func(name string) *ConfigManager {
+		return &ConfigManager{
+			client: cl,
+			NodeConfig: &bootstrapv1.NodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       bootstrapv1.NodeConfigSpec{Network: network},
+			},
+			Log: log.Log,
+		}
+	}
+
+	first := newConfigManager("node-1")
+	out, err := first.withPoolAddresses(ctx, network)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(out.Ethernets[0].Addresses).To(Equal([]string{"192.168.100.3/24"}))
+	g.Expect(out.Ethernets[0].Gateway4).To(Equal("192.168.100.1"))
+	g.Expect(out.Ethernets[0].Nameservers.Addresses).To(Equal([]string{"192.168.100.2"}))
+	g.Expect(network.Ethernets[0].Addresses).To(BeEmpty())
+
+	// The address is kept on the next render
+	out, err = first.withPoolAddresses(ctx, network)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(out.Ethernets[0].Addresses).To(Equal([]string{"192.168.100.3/24"}))
+
+	second := newConfigManager("node-2")
+	out, err = second.withPoolAddresses(ctx, network)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(out.Ethernets[0].Addresses).To(Equal([]string{"192.168.100.4/24"}))
+
+	g.Expect(first.ReleaseIPClaims(ctx)).To(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+	g.Expect(pool.Status.Allocations).To(Equal(map[string]string{"node-2-eno1": "192.168.100.4"}))
+	claims := &bootstrapv1.IPClaimList{}
+	g.Expect(cl.List(ctx, claims)).To(Succeed())
+	g.Expect(claims.Items).To(HaveLen(1))
+	g.Expect(claims.Items[0].Name).To(Equal("node-2-eno1"))
+
+	// Dropping the pool reference releases the address
+	out, err = second.withPoolAddresses(ctx, &bootstrapv1.Network{Ethernets: []bootstrapv1.Ethernet{{Name: "eno1"}}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(out.Ethernets[0].Addresses).To(BeEmpty())
+	released := &bootstrapv1.IPPool{}
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(pool), released)).To(Succeed())
+	g.Expect(released.Status.Allocations).To(BeEmpty())
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	kubeadmtypes "github.com/tmax-cloud/nodeconfig-operator/types"
+	kubeadmv1beta2 "github.com/tmax-cloud/nodeconfig-operator/types/v1beta2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// certificateKeyBytes is the size of the key kubeadm encrypts the uploaded
+// certificates with.
+const certificateKeyBytes = 32
+
+// kubeadmConfigs holds the kubeadm configurations rendered for the node.
+type kubeadmConfigs struct {
+	ClusterConfiguration string
+	InitConfiguration    string
+	UploadCerts          bool
+	JoinConfiguration    string
+
+	// token is the bootstrap token generated for the JoinConfiguration
+	token string
+}
+
+// renderKubeadm renders the kubeadm configurations the role of the node
+// needs, filling in what the controller takes care of.
+func (c *ConfigManager) renderKubeadm(ctx context.Context) (*kubeadmConfigs, error) {
+	configs := &kubeadmConfigs{}
+	kubeadm := c.NodeConfig.Spec.Kubeadm
+	if kubeadm == nil {
+		kubeadm = &bootstrapv1.KubeadmSpec{}
+	}
+
+	var err error
+	if c.NodeConfig.Role() == bootstrapv1.ControlPlaneInitRole {
+		initConfig := &kubeadmv1beta2.InitConfiguration{}
+		if kubeadm.InitConfiguration != nil {
+			initConfig = kubeadm.InitConfiguration.DeepCopy()
+		}
+		clusterConfig := &kubeadmv1beta2.ClusterConfiguration{}
+		if kubeadm.ClusterConfiguration != nil {
+			clusterConfig = kubeadm.ClusterConfiguration.DeepCopy()
+		}
+
+		if initConfig.CertificateKey == "" && kubeadm.CertificateKeySecretRef != nil {
+			if initConfig.CertificateKey, err = c.certificateKey(ctx, true); err != nil {
+				return nil, err
+			}
+		}
+		configs.UploadCerts = initConfig.CertificateKey != ""
+		if clusterConfig.KubernetesVersion == "" {
+			clusterConfig.KubernetesVersion = c.NodeConfig.Spec.KubernetesVersion
+		}
+
+		if configs.InitConfiguration, err = c.toYAML(initConfig); err != nil {
+			return nil, errors.Wrap(err, "failed to marshal init configuration")
+		}
+		if configs.ClusterConfiguration, err = c.toYAML(clusterConfig); err != nil {
+			return nil, errors.Wrap(err, "failed to marshal cluster configuration")
+		}
+		return configs, nil
+	}
+
+	if kubeadm.JoinConfiguration == nil {
+		return configs, nil
+	}
+	joinConfig := kubeadm.JoinConfiguration.DeepCopy()
+	if joinConfig.Discovery.File == nil {
+		if joinConfig.Discovery.BootstrapToken == nil {
+			joinConfig.Discovery.BootstrapToken = &kubeadmv1beta2.BootstrapTokenDiscovery{}
+		}
+		if c.NodeConfig.NeedsBootstrapToken() {
+			if configs.token, err = c.bootstrapToken(ctx); err != nil {
+				return nil, errors.Wrap(err, "failed to get a bootstrap token")
+			}
+			joinConfig.Discovery.BootstrapToken.Token = configs.token
+		}
+		if err = c.fillBootstrapTokenDiscovery(ctx, joinConfig.Discovery.BootstrapToken); err != nil {
+			return nil, errors.Wrap(err, "failed to fill the bootstrap token discovery")
+		}
+	}
+
+	if c.NodeConfig.Role() == bootstrapv1.ControlPlaneJoinRole {
+		if joinConfig.ControlPlane == nil {
+			joinConfig.ControlPlane = &kubeadmv1beta2.JoinControlPlane{}
+		}
+		if joinConfig.ControlPlane.CertificateKey == "" && kubeadm.CertificateKeySecretRef != nil {
+			if joinConfig.ControlPlane.CertificateKey, err = c.certificateKey(ctx, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if configs.JoinConfiguration, err = c.toYAML(joinConfig); err != nil {
+		return nil, errors.Wrap(err, "failed to marshal join configuration")
+	}
+	return configs, nil
+}
+
+// toYAML renders the kubeadm configuration with the kubeadm API version of
+// the Kubernetes version of the node, or as v1beta2 when it is not set.
+func (c *ConfigManager) toYAML(obj runtime.Object) (string, error) {
+	version := c.NodeConfig.Spec.KubernetesVersion
+	if version == "" {
+		return kubeadmv1beta2.ConfigurationToYAML(obj)
+	}
+
+	switch o := obj.(type) {
+	case *kubeadmv1beta2.ClusterConfiguration:
+		return kubeadmtypes.MarshalClusterConfigurationForVersion(o, version)
+	case *kubeadmv1beta2.InitConfiguration:
+		return kubeadmtypes.MarshalInitConfigurationForVersion(o, version)
+	case *kubeadmv1beta2.JoinConfiguration:
+		return kubeadmtypes.MarshalJoinConfigurationForVersion(o, version)
+	}
+	return "", errors.Errorf("unexpected kubeadm configuration type %T", obj)
+}
+
+// certificateKey returns the key held by the certificate key Secret. When
+// create is set, a Secret with a new key is created if there is none.
+func (c *ConfigManager) certificateKey(ctx context.Context, create bool) (string, error) {
+	ref := c.NodeConfig.Spec.Kubeadm.CertificateKeySecretRef
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: c.NodeConfig.Namespace, Name: ref.Name}
+	err := c.client.Get(ctx, key, secret)
+	switch {
+	case err == nil:
+		certificateKey := string(secret.Data[bootstrapv1.CertificateKeySecretKey])
+		if certificateKey == "" {
+			return "", errors.Errorf("the certificate key Secret %s/%s has no %s key",
+				key.Namespace, key.Name, bootstrapv1.CertificateKeySecretKey)
+		}
+		return certificateKey, nil
+	case !apierrors.IsNotFound(err):
+		return "", errors.Wrapf(err, "failed to get the certificate key Secret %s/%s", key.Namespace, key.Name)
+	case !create:
+		return "", errors.Errorf("waiting for the certificate key Secret %s/%s", key.Namespace, key.Name)
+	}
+
+	randBytes := make([]byte, certificateKeyBytes)
+	if _, err := rand.Read(randBytes); err != nil {
+		return "", errors.Wrap(err, "failed to generate a certificate key")
+	}
+	certificateKey := hex.EncodeToString(randBytes)
+
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ref.Name,
+			Namespace: c.NodeConfig.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: bootstrapv1.GroupVersion.String(),
+					Kind:       "NodeConfig",
+					Name:       c.NodeConfig.Name,
+					UID:        c.NodeConfig.UID,
+				},
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			bootstrapv1.CertificateKeySecretKey: []byte(certificateKey),
+		},
+	}
+	if err := c.client.Create(ctx, secret); err != nil {
+		return "", errors.Wrapf(err, "failed to create the certificate key Secret %s/%s", key.Namespace, key.Name)
+	}
+	c.Log.Info("Created the certificate key Secret", "secret", ref.Name)
+	return certificateKey, nil
+}
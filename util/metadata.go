@@ -0,0 +1,143 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// metaDataSecretName returns the name of the metadata secret of the
+// NodeConfig.
+func metaDataSecretName(nodeConfigName string) string {
+	return nodeConfigName + "-metadata"
+}
+
+// CreateMetaData renders the instance metadata of the NodeConfig and stores
+// it in the metadata secret.
+func (c *ConfigManager) CreateMetaData(ctx context.Context) (*corev1.SecretReference, error) {
+	key := client.ObjectKey{Namespace: c.NodeConfig.Namespace, Name: metaDataSecretName(c.NodeConfig.Name)}
+
+	data, err := renderMetaData(c.NodeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.applyDataSecret(ctx, key, bootstrapv1.MetaDataSecretKey, data, "metadata"); err != nil {
+		return nil, err
+	}
+	c.NodeConfig.Status.MetaDataHash = dataHash(data)
+	return &corev1.SecretReference{Name: key.Name, Namespace: key.Namespace}, nil
+}
+
+// renderMetaData returns the metadata of the NodeConfig: its custom keys,
+// the instance-id and the rendered hostnames.
+func renderMetaData(config *bootstrapv1.NodeConfig) ([]byte, error) {
+	metaData := map[string]string{}
+
+	if md := config.Spec.Metadata; md != nil {
+		for k, v := range md.Keys {
+			metaData[k] = v
+		}
+
+		hostname, err := renderHostname(config, md.Hostname)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render hostname")
+		}
+		localHostname, err := renderHostname(config, md.LocalHostname)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render localHostname")
+		}
+		if localHostname == "" {
+			localHostname = hostname
+		}
+		if hostname != "" {
+			metaData[bootstrapv1.MetaDataHostnameKey] = hostname
+		}
+		if localHostname != "" {
+			metaData[bootstrapv1.MetaDataLocalHostnameKey] = localHostname
+		}
+	}
+	metaData[bootstrapv1.MetaDataInstanceIDKey] = string(config.UID)
+
+	out, err := yaml.Marshal(metaData)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal metadata")
+	}
+	return out, nil
+}
+
+// NodeName returns the name the node registers with. That is the
+// nodeRegistration name of the JoinConfiguration or else the hostname of the
+// host: the local hostname rendered from the metadata, the name of the
+// claimed host or the NodeConfig name, which names the BareMetalHost
+// registered for it.
+func NodeName(config *bootstrapv1.NodeConfig) string {
+	if config.Spec.Kubeadm != nil && config.Spec.Kubeadm.JoinConfiguration != nil &&
+		config.Spec.Kubeadm.JoinConfiguration.NodeRegistration.Name != "" {
+		return config.Spec.Kubeadm.JoinConfiguration.NodeRegistration.Name
+	}
+	if md := config.Spec.Metadata; md != nil {
+		hostname := md.LocalHostname
+		if hostname == "" {
+			hostname = md.Hostname
+		}
+		// kubeadm lowercases the hostname it defaults the node name to
+		if name, err := renderHostname(config, hostname); err == nil && name != "" {
+			return strings.ToLower(name)
+		}
+	}
+	if config.Status.HostRef != nil {
+		return config.Status.HostRef.Name
+	}
+	return config.Name
+}
+
+// renderHostname executes the hostname template with the name, namespace
+// and index of the NodeConfig. A template using .Index fails when the
+// NodeConfig has no index label.
+func renderHostname(config *bootstrapv1.NodeConfig, hostname string) (string, error) {
+	if hostname == "" {
+		return "", nil
+	}
+	tmpl, err := template.New("hostname").Option("missingkey=error").Parse(hostname)
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]string{
+		"Name":      config.Name,
+		"Namespace": config.Namespace,
+	}
+	if index, ok := config.Labels[bootstrapv1.NodeIndexLabel]; ok {
+		data["Index"] = index
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	kubeadmv1beta2 "github.com/tmax-cloud/nodeconfig-operator/types/v1beta2"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRenderMetaData(t *testing.T) {
+	var tests = []struct {
+		name     string
+		labels   map[string]string
+		metadata *bootstrapv1.Metadata
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "instance-id only",
+			want: map[string]string{"instance-id": "1234"},
+		},
+		{
+			name:     "local hostname defaults to the hostname",
+			metadata: &bootstrapv1.Metadata{Hostname: "node-a", Keys: map[string]string{"rack": "r1"}},
+			want: map[string]string{
+				"instance-id":    "1234",
+				"hostname":       "node-a",
+				"local-hostname": "node-a",
+				"rack":           "r1",
+			},
+		},
+		{
+			name:   "templated hostnames",
+			labels: map[string]string{bootstrapv1.NodeIndexLabel: "3"},
+			metadata: &bootstrapv1.Metadata{
+				Hostname:      "worker-{{ .Index }}.{{ .Namespace }}.example.com",
+				LocalHostname: "worker-{{ .Index }}",
+			},
+			want: map[string]string{
+				"instance-id":    "1234",
+				"hostname":       "worker-3.default.example.com",
+				"local-hostname": "worker-3",
+			},
+		},
+		{
+			name:     "index without the label",
+			metadata: &bootstrapv1.Metadata{Hostname: "worker-{{ .Index }}"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config := &bootstrapv1.NodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default", UID: "1234", Labels: tt.labels},
+				Spec:       bootstrapv1.NodeConfigSpec{Metadata: tt.metadata},
+			}
+			out, err := renderMetaData(config)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			got := map[string]string{}
+			g.Expect(yaml.Unmarshal(out, &got)).To(Succeed())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestNodeName(t *testing.T) {
+	var tests = []struct {
+		name    string
+		labels  map[string]string
+		spec    bootstrapv1.NodeConfigSpec
+		hostRef *corev1.ObjectReference
+		want    string
+	}{
+		{
+			name: "name of the NodeConfig",
+			want: "node",
+		},
+		{
+			name:    "name of the claimed host",
+			hostRef: &corev1.ObjectReference{Name: "host-4"},
+			want:    "host-4",
+		},
+		{
+			name:   "rendered local hostname",
+			labels: map[string]string{bootstrapv1.NodeIndexLabel: "3"},
+			spec: bootstrapv1.NodeConfigSpec{Metadata: &bootstrapv1.Metadata{
+				Hostname:      "Worker-{{ .Index }}.example.com",
+				LocalHostname: "Worker-{{ .Index }}",
+			}},
+			hostRef: &corev1.ObjectReference{Name: "host-4"},
+			want:    "worker-3",
+		},
+		{
+			name: "nodeRegistration name",
+			spec: bootstrapv1.NodeConfigSpec{
+				Metadata: &bootstrapv1.Metadata{Hostname: "worker"},
+				Kubeadm: &bootstrapv1.KubeadmSpec{JoinConfiguration: &kubeadmv1beta2.JoinConfiguration{
+					NodeRegistration: kubeadmv1beta2.NodeRegistrationOptions{Name: "node-a"},
+				}},
+			},
+			want: "node-a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config := &bootstrapv1.NodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default", Labels: tt.labels},
+				Spec:       tt.spec,
+				Status:     bootstrapv1.NodeConfigStatus{HostRef: tt.hostRef},
+			}
+			g.Expect(NodeName(config)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	"github.com/tmax-cloud/nodeconfig-operator/util/cloudinit"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// networkDataSecretName returns the name of the network data secret of the
+// NodeConfig.
+func networkDataSecretName(nodeConfigName string) string {
+	return nodeConfigName + "-networkdata"
+}
+
+// CreateNetworkData renders the network configuration of the NodeConfig,
+// with the addresses allocated from IPPools, and stores it in the network
+// data secret. It returns nil when the NodeConfig
+// has no network configuration, removing the secret rendered before if any.
+func (c *ConfigManager) CreateNetworkData(ctx context.Context) (*corev1.SecretReference, error) {
+	key := client.ObjectKey{Namespace: c.NodeConfig.Namespace, Name: networkDataSecretName(c.NodeConfig.Name)}
+
+	network, err := c.withPoolAddresses(ctx, c.NodeConfig.Spec.Network)
+	if err != nil {
+		return nil, err
+	}
+	if network == nil {
+		if c.NodeConfig.Status.NetworkData != nil {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+			if err := c.client.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+				return nil, errors.Wrapf(err, "failed to delete network data secret %s/%s", key.Namespace, key.Name)
+			}
+		}
+		c.NodeConfig.Status.NetworkDataHash = ""
+		return nil, nil
+	}
+
+	data, err := cloudinit.NewNetworkConfig(network)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.applyDataSecret(ctx, key, bootstrapv1.NetworkDataSecretKey, data, "network data"); err != nil {
+		return nil, err
+	}
+	c.NodeConfig.Status.NetworkDataHash = dataHash(data)
+	return &corev1.SecretReference{Name: key.Name, Namespace: key.Namespace}, nil
+}
+
+// applyDataSecret creates the secret owned by the NodeConfig that holds data
+// under dataKey, or updates it when the data changed. kind names the data in
+// logs and errors.
+func (c *ConfigManager) applyDataSecret(ctx context.Context, key client.ObjectKey, dataKey string, data []byte, kind string) error {
+	secret := &corev1.Secret{}
+	err := c.client.Get(ctx, key, secret)
+	switch {
+	case apierrors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: bootstrapv1.GroupVersion.String(),
+						Kind:       "NodeConfig",
+						Name:       c.NodeConfig.Name,
+						UID:        c.NodeConfig.UID,
+						Controller: pointer.BoolPtr(true),
+					},
+				},
+			},
+			Data: map[string][]byte{
+				dataKey: data,
+			},
+		}
+		if err := c.client.Create(ctx, secret); err != nil {
+			return errors.Wrapf(err, "failed to create %s secret %s/%s", kind, key.Namespace, key.Name)
+		}
+		c.Log.Info("Created the "+kind+" secret", "secret", key.Name)
+	case err != nil:
+		return errors.Wrapf(err, "failed to get %s secret %s/%s", kind, key.Namespace, key.Name)
+	case !bytes.Equal(secret.Data[dataKey], data):
+		secret.Data = map[string][]byte{
+			dataKey: data,
+		}
+		if err := c.client.Update(ctx, secret); err != nil {
+			return errors.Wrapf(err, "failed to update %s secret %s/%s", kind, key.Namespace, key.Name)
+		}
+		c.Log.Info("Updated the "+kind+" secret", "secret", key.Name)
+	}
+
+	return nil
+}
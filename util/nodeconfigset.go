@@ -0,0 +1,136 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"text/template"
+
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// SetHosts returns the hosts of the NodeConfigSet, those of its spec
+// followed by those of its inventory. The credentials of the set are used
+// for the hosts that set none.
+func SetHosts(ctx context.Context, cl client.Client, set *bootstrapv1.NodeConfigSet) ([]bootstrapv1.BMC, error) {
+	hosts := make([]bootstrapv1.BMC, 0, len(set.Spec.Hosts))
+	for i := range set.Spec.Hosts {
+		hosts = append(hosts, *set.Spec.Hosts[i].DeepCopy())
+	}
+
+	if ref := set.Spec.InventoryRef; ref != nil {
+		value, ok, err := getConfigMapKey(ctx, cl, set.Namespace, ref)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			var inventory []bootstrapv1.BMC
+			if err := yaml.UnmarshalStrict([]byte(value), &inventory); err != nil {
+				return nil, errors.Wrapf(err, "invalid inventory in ConfigMap %s/%s key %s", set.Namespace, ref.Name, ref.Key)
+			}
+			hosts = append(hosts, inventory...)
+		}
+	}
+
+	addresses := map[string]bool{}
+	for i := range hosts {
+		host := &hosts[i]
+		if host.Address == "" {
+			return nil, errors.Errorf("host %d has no BMC address", i)
+		}
+		if addresses[host.Address] {
+			return nil, errors.Errorf("BMC address %s is listed twice", host.Address)
+		}
+		addresses[host.Address] = true
+
+		if host.CredentialsSecretRef == nil && host.Username == "" && set.Spec.CredentialsSecretRef != nil {
+			host.CredentialsSecretRef = set.Spec.CredentialsSecretRef.DeepCopy()
+		}
+	}
+	return hosts, nil
+}
+
+// SetNodeConfigName executes the name template of the NodeConfigSet with
+// the name and namespace of the set and the index of the host.
+func SetNodeConfigName(set *bootstrapv1.NodeConfigSet, index int) (string, error) {
+	nameTemplate := set.Spec.NameTemplate
+	if nameTemplate == "" {
+		nameTemplate = bootstrapv1.DefaultNodeConfigSetNameTemplate
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid nameTemplate")
+	}
+
+	data := map[string]string{
+		"Name":      set.Name,
+		"Namespace": set.Namespace,
+		"Index":     strconv.Itoa(index),
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", errors.Wrap(err, "failed to render nameTemplate")
+	}
+	return out.String(), nil
+}
+
+// NewSetNodeConfig returns the NodeConfig of the host with the given index,
+// created from the template of the NodeConfigSet. It is labelled with the
+// name of the set and the index, and controlled by the set.
+func NewSetNodeConfig(set *bootstrapv1.NodeConfigSet, tmpl *bootstrapv1.NodeConfigTemplate,
+	host *bootstrapv1.BMC, index int, name string) *bootstrapv1.NodeConfig {
+	resource := tmpl.Spec.Template.DeepCopy()
+
+	labels := resource.ObjectMeta.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[bootstrapv1.NodeConfigSetLabel] = set.Name
+	labels[bootstrapv1.NodeIndexLabel] = strconv.Itoa(index)
+
+	config := &bootstrapv1.NodeConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   set.Namespace,
+			Labels:      labels,
+			Annotations: resource.ObjectMeta.Annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(set, bootstrapv1.GroupVersion.WithKind("NodeConfigSet")),
+			},
+		},
+		Spec: resource.Spec,
+	}
+	config.Spec.BMC = host.DeepCopy()
+	return config
+}
+
+// SetNodeConfigIndex returns the index of a NodeConfig of a NodeConfigSet,
+// or false when it has no valid index label.
+func SetNodeConfigIndex(config *bootstrapv1.NodeConfig) (int, bool) {
+	index, err := strconv.Atoi(config.Labels[bootstrapv1.NodeIndexLabel])
+	if err != nil || index < 0 {
+		return 0, false
+	}
+	return index, true
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/tmax-cloud/nodeconfig-operator/util/cloudinit"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// packageKeys reads the GPG keys the package repositories of the NodeConfig
+// refer to from their ConfigMaps.
+func (c *ConfigManager) packageKeys(ctx context.Context) (cloudinit.PackageKeys, error) {
+	keys := cloudinit.PackageKeys{}
+	packages := c.NodeConfig.Spec.Packages
+	if packages == nil {
+		return keys, nil
+	}
+
+	for _, r := range packages.YumRepos {
+		if r.GPGKeyRef == nil {
+			continue
+		}
+		key, ok, err := c.configMapKey(ctx, r.GPGKeyRef)
+		if err != nil {
+			return keys, errors.Wrapf(err, "failed to read the GPG key of yum repository %s", r.ID)
+		}
+		if !ok {
+			continue
+		}
+		if keys.Yum == nil {
+			keys.Yum = map[string]string{}
+		}
+		keys.Yum[r.ID] = key
+	}
+	for _, s := range packages.AptSources {
+		if s.KeyRef == nil {
+			continue
+		}
+		key, ok, err := c.configMapKey(ctx, s.KeyRef)
+		if err != nil {
+			return keys, errors.Wrapf(err, "failed to read the key of apt source %s", s.Name)
+		}
+		if !ok {
+			continue
+		}
+		if keys.Apt == nil {
+			keys.Apt = map[string]string{}
+		}
+		keys.Apt[s.Name] = key
+	}
+	return keys, nil
+}
+
+// configMapKey returns the value selected from a ConfigMap in the NodeConfig
+// namespace, looking in its binary data too. It returns false when an
+// optional ConfigMap or key is missing.
+func (c *ConfigManager) configMapKey(ctx context.Context, selector *corev1.ConfigMapKeySelector) (string, bool, error) {
+	return getConfigMapKey(ctx, c.client, c.NodeConfig.Namespace, selector)
+}
+
+// getConfigMapKey returns the value selected from a ConfigMap in the given
+// namespace, as configMapKey does.
+func getConfigMapKey(ctx context.Context, cl client.Client, namespace string, selector *corev1.ConfigMapKeySelector) (string, bool, error) {
+	optional := selector.Optional != nil && *selector.Optional
+	key := client.ObjectKey{Namespace: namespace, Name: selector.Name}
+
+	configMap := &corev1.ConfigMap{}
+	if err := cl.Get(ctx, key, configMap); err != nil {
+		if apierrors.IsNotFound(err) && optional {
+			return "", false, nil
+		}
+		return "", false, errors.Wrapf(err, "failed to get ConfigMap %s/%s", key.Namespace, key.Name)
+	}
+	if value, ok := configMap.Data[selector.Key]; ok {
+		return value, true, nil
+	}
+	if value, ok := configMap.BinaryData[selector.Key]; ok {
+		return string(value), true, nil
+	}
+	if optional {
+		return "", false, nil
+	}
+	return "", false, errors.Errorf("ConfigMap %s/%s has no key %s", key.Namespace, key.Name, selector.Key)
+}
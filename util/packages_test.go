@@ -0,0 +1,81 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestPackageKeys(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	keys := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "keys", Namespace: "default"},
+		Data:       map[string]string{"internal": "internal key"},
+		BinaryData: map[string][]byte{"kubernetes": []byte("kubernetes key")},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(keys).Build()
+
+	selector := func(name, key string, optional bool) *corev1.ConfigMapKeySelector {
+		return &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Key:                  key,
+			Optional:             pointer.BoolPtr(optional),
+		}
+	}
+	newConfigManager := func(packages *bootstrapv1.Packages) *ConfigManager {
+		return &ConfigManager{
+			client: cl,
+			NodeConfig: &bootstrapv1.NodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default"},
+				Spec:       bootstrapv1.NodeConfigSpec{Packages: packages},
+			},
+			Log: log.Log,
+		}
+	}
+
+	got, err := newConfigManager(&bootstrapv1.Packages{
+		YumRepos: []bootstrapv1.YumRepo{
+			{ID: "internal", GPGKeyRef: selector("keys", "internal", false)},
+			{ID: "optional", GPGKeyRef: selector("missing", "key", true)},
+		},
+		AptSources: []bootstrapv1.AptSource{
+			{Name: "kubernetes", KeyRef: selector("keys", "kubernetes", false)},
+		},
+	}).packageKeys(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got.Yum).To(Equal(map[string]string{"internal": "internal key"}))
+	g.Expect(got.Apt).To(Equal(map[string]string{"kubernetes": "kubernetes key"}))
+
+	_, err = newConfigManager(&bootstrapv1.Packages{
+		YumRepos: []bootstrapv1.YumRepo{{ID: "internal", GPGKeyRef: selector("keys", "missing", false)}},
+	}).packageKeys(ctx)
+	g.Expect(err).To(MatchError(ContainSubstring("ConfigMap default/keys has no key missing")))
+}
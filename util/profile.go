@@ -0,0 +1,142 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ApplyProfile merges the NodeConfigProfile of the NodeConfig into its spec
+// and records it in the status. The merged spec is only meant for rendering:
+// the caller restores the spec before the NodeConfig is written back.
+func (c *ConfigManager) ApplyProfile(ctx context.Context) error {
+	profile, err := c.findProfile(ctx)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		c.NodeConfig.Status.AppliedProfile = nil
+		return nil
+	}
+
+	mergeProfile(&c.NodeConfig.Spec, &profile.Spec)
+	c.NodeConfig.Status.AppliedProfile = &bootstrapv1.AppliedProfile{
+		Name:       profile.Name,
+		Generation: profile.Generation,
+	}
+	return nil
+}
+
+// findProfile returns the profile the NodeConfig refers to, or else the one
+// selecting it. It fails when several profiles select the NodeConfig.
+func (c *ConfigManager) findProfile(ctx context.Context) (*bootstrapv1.NodeConfigProfile, error) {
+	if ref := c.NodeConfig.Spec.ProfileRef; ref != nil {
+		profile := &bootstrapv1.NodeConfigProfile{}
+		if err := c.client.Get(ctx, client.ObjectKey{Name: ref.Name}, profile); err != nil {
+			return nil, errors.Wrapf(err, "failed to get NodeConfigProfile %s", ref.Name)
+		}
+		return profile, nil
+	}
+
+	profiles := &bootstrapv1.NodeConfigProfileList{}
+	if err := c.client.List(ctx, profiles); err != nil {
+		return nil, errors.Wrap(err, "failed to list NodeConfigProfiles")
+	}
+	var found *bootstrapv1.NodeConfigProfile
+	for i := range profiles.Items {
+		profile := &profiles.Items[i]
+		ok, err := ProfileSelects(profile, c.NodeConfig)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if found != nil {
+			return nil, errors.Errorf("both NodeConfigProfiles %s and %s select the NodeConfig. set profileRef", found.Name, profile.Name)
+		}
+		found = profile
+	}
+	return found, nil
+}
+
+// ProfileSelects returns true when the selector of the profile matches the
+// labels of the NodeConfig. A profile without a selector selects nothing, an
+// empty selector selects every NodeConfig.
+func ProfileSelects(profile *bootstrapv1.NodeConfigProfile, config *bootstrapv1.NodeConfig) (bool, error) {
+	if profile.Spec.Selector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(profile.Spec.Selector)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid selector of NodeConfigProfile %s", profile.Name)
+	}
+	return selector.Matches(labels.Set(config.Labels)), nil
+}
+
+// mergeProfile merges the profile into the NodeConfig spec:
+//   - files are merged by path and users by name, those of the NodeConfig
+//     replacing those of the profile, which come first
+//   - the commands of the profile run before those of the NodeConfig
+//   - the image and the NTP configuration of the NodeConfig win over those
+//     of the profile
+func mergeProfile(spec *bootstrapv1.NodeConfigSpec, profile *bootstrapv1.NodeConfigProfileSpec) {
+	if spec.Image == nil && profile.Image != nil {
+		spec.Image = profile.Image.DeepCopy()
+	}
+	if spec.NTP == nil && profile.NTP != nil {
+		spec.NTP = profile.NTP.DeepCopy()
+	}
+
+	if len(profile.CloudInitCommands) > 0 {
+		spec.CloudInitCommands = append(append([]string{}, profile.CloudInitCommands...), spec.CloudInitCommands...)
+	}
+
+	paths := map[string]bool{}
+	for _, f := range spec.Files {
+		paths[f.Path] = true
+	}
+	var files []bootstrapv1.File
+	for _, f := range profile.Files {
+		if !paths[f.Path] {
+			files = append(files, *f.DeepCopy())
+		}
+	}
+	if len(files) > 0 {
+		spec.Files = append(files, spec.Files...)
+	}
+
+	names := map[string]bool{}
+	for _, u := range spec.Users {
+		names[u.Name] = true
+	}
+	var users []bootstrapv1.User
+	for _, u := range profile.Users {
+		if !names[u.Name] {
+			users = append(users, *u.DeepCopy())
+		}
+	}
+	if len(users) > 0 {
+		spec.Users = append(users, spec.Users...)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	bootstrapv1 "github.com/tmax-cloud/nodeconfig-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestMergeProfile(t *testing.T) {
+	g := NewWithT(t)
+
+	profile := &bootstrapv1.NodeConfigProfileSpec{
+		Image: &bootstrapv1.Image{URL: "http://images/profile.qcow2", Checksum: "http://images/profile.qcow2.md5sum"},
+		Files: []bootstrapv1.File{
+			{Path: "/etc/pki/ca-trust/source/anchors/corp.crt", Content: "corporate CA"},
+			{Path: "/etc/motd", Content: "profile motd"},
+		},
+		Users: []bootstrapv1.User{
+			{Name: "admin", Sudo: pointer.StringPtr("ALL=(ALL) NOPASSWD:ALL")},
+			{Name: "tmax"},
+		},
+		CloudInitCommands: []string{"update-ca-trust"},
+		NTP:               &bootstrapv1.NTP{Servers: []string{"ntp.corp.example.com"}},
+	}
+	spec := &bootstrapv1.NodeConfigSpec{
+		Files:             []bootstrapv1.File{{Path: "/etc/motd", Content: "node motd"}},
+		Users:             []bootstrapv1.User{{Name: "tmax", Groups: pointer.StringPtr("wheel")}},
+		CloudInitCommands: []string{"systemctl enable --now kubelet"},
+		NTP:               &bootstrapv1.NTP{Servers: []string{"0.pool.ntp.org"}},
+	}
+	mergeProfile(spec, profile)
+
+	g.Expect(spec.Image).To(Equal(profile.Image))
+	g.Expect(spec.NTP.Servers).To(Equal([]string{"0.pool.ntp.org"}))
+	g.Expect(spec.CloudInitCommands).To(Equal([]string{"update-ca-trust", "systemctl enable --now kubelet"}))
+	g.Expect(spec.Files).To(Equal([]bootstrapv1.File{
+		{Path: "/etc/pki/ca-trust/source/anchors/corp.crt", Content: "corporate CA"},
+		{Path: "/etc/motd", Content: "node motd"},
+	}))
+	g.Expect(spec.Users).To(Equal([]bootstrapv1.User{
+		{Name: "admin", Sudo: pointer.StringPtr("ALL=(ALL) NOPASSWD:ALL")},
+		{Name: "tmax", Groups: pointer.StringPtr("wheel")},
+	}))
+
+	// The profile is left as it was
+	g.Expect(profile.Files).To(HaveLen(2))
+	g.Expect(profile.CloudInitCommands).To(Equal([]string{"update-ca-trust"}))
+}
+
+func TestApplyProfile(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	NewWithT(t).Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+	newProfile := func(name string, selector *metav1.LabelSelector) *bootstrapv1.NodeConfigProfile {
+		return &bootstrapv1.NodeConfigProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 2},
+			Spec: bootstrapv1.NodeConfigProfileSpec{
+				Selector:          selector,
+				CloudInitCommands: []string{"echo " + name},
+			},
+		}
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newProfile("workers", &metav1.LabelSelector{MatchLabels: map[string]string{"role": "worker"}}),
+		newProfile("gpu", &metav1.LabelSelector{MatchLabels: map[string]string{"gpu": "true"}}),
+		newProfile("manual", nil),
+	).Build()
+
+	var tests = []struct {
+		name       string
+		labels     map[string]string
+		profileRef string
+		want       *bootstrapv1.AppliedProfile
+		wantErr    bool
+	}{
+		{
+			name: "no profile",
+		},
+		{
+			name:   "selected by labels",
+			labels: map[string]string{"role": "worker"},
+			want:   &bootstrapv1.AppliedProfile{Name: "workers", Generation: 2},
+		},
+		{
+			name:    "selected twice",
+			labels:  map[string]string{"role": "worker", "gpu": "true"},
+			wantErr: true,
+		},
+		{
+			name:       "profileRef wins",
+			labels:     map[string]string{"role": "worker", "gpu": "true"},
+			profileRef: "manual",
+			want:       &bootstrapv1.AppliedProfile{Name: "manual", Generation: 2},
+		},
+		{
+			name:       "unknown profileRef",
+			profileRef: "missing",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			config := &bootstrapv1.NodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "default", Labels: tt.labels},
+			}
+			if tt.profileRef != "" {
+				config.Spec.ProfileRef = &corev1.LocalObjectReference{Name: tt.profileRef}
+			}
+			c := &ConfigManager{client: cl, NodeConfig: config, Log: log.Log}
+
+			err := c.ApplyProfile(ctx)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(config.Status.AppliedProfile).To(Equal(tt.want))
+			if tt.want != nil {
+				g.Expect(config.Spec.CloudInitCommands).To(Equal([]string{"echo " + tt.want.Name}))
+			}
+		})
+	}
+}